@@ -0,0 +1,9 @@
+package example
+
+const (
+	Debug, Info = iota, iota + 1
+	Warn, Error
+	_, Fatal
+)
+
+var buffered, Unbuffered int
@@ -0,0 +1,12 @@
+package example
+
+const MaxRetries = 3 // retries before giving up
+
+const (
+	MinPort = 1024  // first unprivileged port
+	MaxPort = 65535 /* highest port */
+)
+
+type Limits struct {
+	Retries int // zero means MaxRetries
+}
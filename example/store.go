@@ -0,0 +1,13 @@
+package example
+
+// Store stores values by key.
+type Store interface {
+	// Get returns the value stored for key.
+	Get(key string) (string, error)
+	// Put stores value for key.
+	Put(key, value string) error
+}
+
+type MemStore struct {
+	values map[string]string
+}
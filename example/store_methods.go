@@ -0,0 +1,18 @@
+package example
+
+func (m *MemStore) Get(key string) (string, error) {
+	return m.values[key], nil
+}
+
+func (m *MemStore) Put(key, value string) error {
+	m.values[key] = value
+	return nil
+}
+
+func (*MemStore) Close() error {
+	return nil
+}
+
+func (MemStore) Name() string {
+	return "mem"
+}
@@ -1,8 +1,16 @@
 module github.com/xiaoyuanhao/godoc-repair
 
-go 1.17
+go 1.18
+
+require (
+	github.com/dave/dst v0.27.3
+	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/dave/dst v0.26.2
 	github.com/stretchr/testify v1.7.2 // indirect
+	golang.org/x/sys v0.0.0-20220722155257-8c9f86f7a55f // indirect
+	golang.org/x/tools v0.1.12 // indirect
+	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
 )
@@ -0,0 +1,109 @@
+package repair
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// baselineEntry identifies a finding independently of its position, so edits elsewhere in a file keep it valid.
+type baselineEntry struct {
+	// Dir is the directory of the package relative to the code path.
+	Dir     string `json:"dir"`
+	Package string `json:"package"`
+	Symbol  string `json:"symbol"`
+	Kind    string `json:"kind"`
+	Rule    string `json:"rule"`
+}
+
+// baseline holds the findings grandfathered by --baseline.
+type baseline struct {
+	Findings []baselineEntry `json:"findings"`
+}
+
+// baselineKey returns the entry of f.
+func baselineKey(f finding) baselineEntry {
+	return baselineEntry{Dir: path.Dir(f.pos.Filename), Package: f.pkg, Symbol: f.symbol, Kind: f.kind, Rule: f.rule}
+}
+
+// loadBaseline reads the baseline of fileName, a missing file is an empty baseline.
+func loadBaseline(fileName string) (baseline, error) {
+	var b baseline
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return b, fmt.Errorf("failed reading baseline %s: %v", fileName, err)
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return b, fmt.Errorf("failed parsing baseline %s: %v", fileName, err)
+	}
+	return b, nil
+}
+
+// saveBaseline writes the entries to fileName in order, so the file diffs well.
+func saveBaseline(fileName string, entries []baselineEntry) error {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Dir != b.Dir {
+			return a.Dir < b.Dir
+		}
+		if a.Symbol != b.Symbol {
+			return a.Symbol < b.Symbol
+		}
+		return a.Rule < b.Rule
+	})
+	data, err := json.MarshalIndent(baseline{Findings: entries}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed encoding baseline: %v", err)
+	}
+	if err := newDirFS(filepath.Dir(fileName)).Write(filepath.Base(fileName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed writing baseline %s: %v", fileName, err)
+	}
+	return nil
+}
+
+// applyBaseline drops the findings grandfathered by b and returns its stale entries,
+// which match no finding because their symbol was documented or removed.
+func applyBaseline(b baseline) []baselineEntry {
+	known := make(map[baselineEntry]bool)
+	for _, e := range b.Findings {
+		known[e] = true
+	}
+	found := make(map[baselineEntry]bool)
+	var remaining []finding
+	for _, f := range findings {
+		key := baselineKey(f)
+		found[key] = true
+		if !known[key] {
+			remaining = append(remaining, f)
+		}
+	}
+	findings = remaining
+	var stale []baselineEntry
+	for _, e := range b.Findings {
+		if !found[e] {
+			stale = append(stale, e)
+		}
+	}
+	return stale
+}
+
+// currentBaseline returns the entries of the findings of the run.
+func currentBaseline() []baselineEntry {
+	entries := []baselineEntry{}
+	seen := make(map[baselineEntry]bool)
+	for _, f := range findings {
+		if key := baselineKey(f); !seen[key] {
+			seen[key] = true
+			entries = append(entries, key)
+		}
+	}
+	return entries
+}
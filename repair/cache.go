@@ -0,0 +1,118 @@
+package repair
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const cacheFile = "godoc-repair-cache.json"
+
+// cache records the files which were fully documented in earlier runs, so they are not parsed again
+// until their content changes. Entries are only valid for the options they were recorded with.
+type cache struct {
+	Options string            `json:"options"`
+	Files   map[string]string `json:"files"`
+}
+
+// fileCache is the cache of the run, nil when caching is disabled.
+var fileCache *cache
+
+// loadCache reads the cache from dir, an unreadable cache or one recorded with other options
+// starts over empty.
+func loadCache(dir string) *cache {
+	c := &cache{Options: optionsHash(), Files: make(map[string]string)}
+	data, err := os.ReadFile(filepath.Join(dir, cacheFile))
+	if err != nil {
+		return c
+	}
+	var stored cache
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Options != c.Options || stored.Files == nil {
+		return c
+	}
+	stored.Options = c.Options
+	return &stored
+}
+
+// save writes the cache to dir.
+func (c *cache) save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed creating cache directory %s: %v", dir, err)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, cacheFile), data, 0644)
+}
+
+// documented reports whether the file with content src was recorded as fully documented.
+func (c *cache) documented(fileName string, src []byte) bool {
+	if c == nil {
+		return false
+	}
+	hash, ok := c.Files[cacheKey(fileName)]
+	return ok && hash == contentHash(src)
+}
+
+// record marks the file with content src as fully documented, or forgets it.
+func (c *cache) record(fileName string, src []byte, documented bool) {
+	if c == nil {
+		return
+	}
+	if documented {
+		c.Files[cacheKey(fileName)] = contentHash(src)
+	} else {
+		delete(c.Files, cacheKey(fileName))
+	}
+}
+
+// cacheKey returns the absolute path of fileName, which is relative to the code path.
+func cacheKey(fileName string) string {
+	key, err := filepath.Abs(filepath.Join(codePath, filepath.FromSlash(fileName)))
+	if err != nil {
+		return fileName
+	}
+	return key
+}
+
+func contentHash(src []byte) string {
+	sum := sha256.Sum256(src)
+	return hex.EncodeToString(sum[:])
+}
+
+// optionsHash hashes the options which influence the output, so changing any of them invalidates the cache.
+func optionsHash() string {
+	var options []string
+	flags.VisitAll(func(f *flag.Flag) {
+		switch f.Name {
+		case "code-path", "cache-dir", "out-dir", "report-out", "manifest", "plan", "patch-out", "apply", "undo":
+			return
+		}
+		options = append(options, f.Name+"="+f.Value.String())
+	})
+	sort.Strings(options)
+	return contentHash([]byte(strings.Join(options, "\n")))
+}
+
+// cachedFilter skips files recorded as fully documented in the cache.
+// The package comment and the symbol table of --implements need to see every file of a package,
+// --deprecate targets documented symbols and --require-type-overview counts the methods of documented types,
+// so nothing is skipped for them.
+func cachedFilter(fsys fs.FS, fileName string) bool {
+	if fileCache == nil || packageComment || packageOnly || implementsDocs || deprecations != nil || requireTypeOverview {
+		return true
+	}
+	src, err := fs.ReadFile(fsys, fileName)
+	if err != nil {
+		return true
+	}
+	return !fileCache.documented(fileName, src)
+}
@@ -0,0 +1,182 @@
+package repair
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Keys of the messages of a catalog.
+const (
+	msgPlaceholder = "placeholder"
+	msgPackage     = "package"
+	msgTodo        = "todo"
+	msgDescription = "description"
+	msgType        = "type"
+	msgTypeVowel   = "type-vowel"
+	msgTypePlural  = "type-plural"
+	msgOptions     = "options"
+	msgVariadic    = "variadic"
+	msgContext     = "context"
+	msgErrorAction = "error-action"
+	msgAlias       = "alias"
+	msgSlice       = "slice"
+	msgMap         = "map"
+	msgPointer     = "pointer"
+	msgResults     = "results"
+	msgAnd         = "and"
+	msgValueAlias  = "value-alias"
+	msgField       = "field"
+	msgCallback    = "callback"
+	msgCallbackOn  = "callback-on"
+	msgCallbackOnV = "callback-on-vowel"
+	msgBefore      = "callback-before"
+	msgAfter       = "callback-after"
+	msgConstraint  = "constraint"
+	msgConstructor = "constructor"
+	msgPeriod      = "period"
+)
+
+// messages is a catalog of the canned phrases used in generated comments, keyed by message key.
+type messages map[string]string
+
+// messageVerbs is the number of %s verbs each message must contain.
+var messageVerbs = map[string]int{
+	msgPlaceholder: 1,
+	msgPackage:     1,
+	msgTodo:        1,
+	msgDescription: 0,
+	msgType:        1,
+	msgTypeVowel:   1,
+	msgTypePlural:  1,
+	msgOptions:     1,
+	msgVariadic:    1,
+	msgContext:     1,
+	msgErrorAction: 2,
+	msgAlias:       1,
+	msgSlice:       1,
+	msgMap:         2,
+	msgPointer:     1,
+	msgResults:     2,
+	msgAnd:         0,
+	msgValueAlias:  1,
+	msgField:       1,
+	msgCallback:    1,
+	msgCallbackOn:  1,
+	msgCallbackOnV: 1,
+	msgBefore:      1,
+	msgAfter:       1,
+	msgConstraint:  1,
+	msgConstructor: 1,
+	msgPeriod:      0,
+}
+
+// catalogs are the built-in catalogs selectable with -lang.
+var catalogs = map[string]messages{
+	"en": {
+		msgPlaceholder: defaultCommentFormat,
+		msgPackage:     "// Package %s missing godoc.",
+		msgTodo:        "// %%s TODO(%s): add documentation.",
+		msgDescription: "missing description.",
+		msgType:        "is a %s.",
+		msgTypeVowel:   "is an %s.",
+		msgTypePlural:  "holds %s.",
+		msgOptions:     defaultOptionsClause,
+		msgVariadic:    defaultVariadicFormat,
+		msgContext:     defaultContextFormat,
+		msgErrorAction: "%s the %s and returns any error.",
+		msgAlias:       "is an alias for %s; see that type for details.",
+		msgSlice:       "is a slice of %s.",
+		msgMap:         "is a map from %s to %s.",
+		msgPointer:     "is a pointer to %s.",
+		msgResults:     "%s and returns %s",
+		msgAnd:         " and ",
+		msgValueAlias:  "is an alias for %s.",
+		msgField:       "is the %s.",
+		msgCallback:    "is called to %s.",
+		msgCallbackOn:  "is called when a %s occurs.",
+		msgCallbackOnV: "is called when an %s occurs.",
+		msgBefore:      "is called before %s.",
+		msgAfter:       "is called after %s.",
+		msgConstraint:  "is a constraint permitting %s.",
+		msgConstructor: "returns a new %s",
+		msgPeriod:      ".",
+	},
+	"ja": {
+		msgPlaceholder: "// %s のドキュメントはありません。",
+		msgPackage:     "// Package %s のドキュメントはありません。",
+		msgTodo:        "// %%s TODO(%s): ドキュメントを追加してください。",
+		msgDescription: "の説明はまだありません。",
+		msgType:        "は%sです。",
+		msgTypeVowel:   "は%sです。",
+		msgTypePlural:  "は%sを保持します。",
+		msgOptions:     "%s で追加の動作を設定できます",
+		msgVariadic:    "可変個の %s を受け取ります",
+		msgContext:     "%s はキャンセルとデッドラインに使用されます。",
+		msgErrorAction: "%s (%s) を実行し、エラーがあれば返します。",
+		msgAlias:       "は %s の別名です。詳細はその型を参照してください。",
+		msgSlice:       "は %s のスライスです。",
+		msgMap:         "は %s から %s へのマップです。",
+		msgPointer:     "は %s へのポインタです。",
+		msgResults:     "%s。%s を返します",
+		msgAnd:         "と",
+		msgValueAlias:  "は %s の別名です。",
+		msgField:       "は%sです。",
+		msgCallback:    "は%sために呼び出されます。",
+		msgCallbackOn:  "は%sが発生したときに呼び出されます。",
+		msgCallbackOnV: "は%sが発生したときに呼び出されます。",
+		msgBefore:      "は%sの前に呼び出されます。",
+		msgAfter:       "は%sの後に呼び出されます。",
+		msgConstraint:  "は%sを許可する制約です。",
+		msgConstructor: "は新しい %s を返します",
+		msgPeriod:      "。",
+	},
+}
+
+// catalog is the catalog in use.
+var catalog = catalogs["en"]
+
+// loadCatalog returns the built-in catalog lang, or reads a JSON file mapping message keys to formats.
+// Messages missing from a file fall back to English.
+func loadCatalog(lang string) (messages, error) {
+	if m, ok := catalogs[lang]; ok {
+		return m, nil
+	}
+	data, err := os.ReadFile(lang)
+	if err != nil {
+		return nil, fmt.Errorf("unknown language %q and failed reading it as a catalog file: %v", lang, err)
+	}
+	var m messages
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed parsing catalog %s: %v", lang, err)
+	}
+	for key, format := range catalogs["en"] {
+		if _, ok := m[key]; !ok {
+			m[key] = format
+		}
+	}
+	if err := validateCatalog(m); err != nil {
+		return nil, fmt.Errorf("invalid catalog %s: %v", lang, err)
+	}
+	return m, nil
+}
+
+// validateCatalog checks that m only contains known messages with the expected verbs.
+func validateCatalog(m messages) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		verbs, ok := messageVerbs[key]
+		if !ok {
+			return fmt.Errorf("unknown message %q", key)
+		}
+		if err := validatePrintf(m[key], verbs); err != nil {
+			return fmt.Errorf("message %q: %v", key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package repair
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+)
+
+// change is a file rewritten by the run, computed first and written once the whole tree was processed.
+type change struct {
+	fsys     writeFS
+	fileName string
+	// pkg is the name of the package of the file.
+	pkg string
+	// source is the hash of the content the change was computed from, empty for a new file.
+	source string
+	data   []byte
+	perm   fs.FileMode
+	// edits are the docs inserted or rewritten in the file.
+	edits []edit
+}
+
+// pending holds the changes of the run in the order they were computed.
+var pending []change
+
+// maxChangesSample is how many paths are printed when --max-changes is exceeded.
+const maxChangesSample = 10
+
+// Modes of --max-changes-mode.
+const (
+	// changesAbort changes nothing when there are more changes than --max-changes.
+	changesAbort = "abort"
+	// changesTruncate changes the first --max-changes files and leaves the others for later runs.
+	changesTruncate = "truncate"
+)
+
+// stage records that fileName of package pkg in fsys is to be written with data, the docs of edits being changed.
+// source is the hash of the content data was computed from, empty if the file does not exist.
+func stage(fsys writeFS, fileName, pkg, source string, data []byte, perm fs.FileMode, edits []edit) {
+	pending = append(pending, change{fsys: fsys, fileName: fileName, pkg: pkg, source: source, data: data, perm: perm, edits: edits})
+}
+
+// stale reports whether the file of c changed since c was computed, e.g. saved by an editor during the run.
+func stale(c change) (bool, error) {
+	src, err := fs.ReadFile(c.fsys, c.fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c.source != "", nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed reading file %s: %v", c.fileName, err)
+	}
+	return contentHash(src) != c.source, nil
+}
+
+// checkChanges returns an error listing a sample of the pending changes when there are more
+// than --max-changes of them and --force is not given. With --max-changes-mode truncate only
+// the first --max-changes changes are kept instead.
+func checkChanges() error {
+	if maxChanges <= 0 || len(pending) <= maxChanges || force {
+		return nil
+	}
+	if maxChangesMode == changesTruncate {
+		log.Printf("Changing %d of %d files, %d are left for later runs by --max-changes", maxChanges, len(pending), len(pending)-maxChanges)
+		pending = pending[:maxChanges]
+		return nil
+	}
+	var sample []string
+	for i, c := range pending {
+		if i == maxChangesSample {
+			sample = append(sample, "...")
+			break
+		}
+		sample = append(sample, "  "+c.fileName)
+	}
+	return fmt.Errorf("refusing to change %d files, more than --max-changes %d, use --force to change them anyway:\n%s",
+		len(pending), maxChanges, strings.Join(sample, "\n"))
+}
+
+// applyChanges writes the pending changes with the outputWriter, or back in place, and records those in place
+// in the journal. Files changed in place since they were read are not written unless --force-stale-write is given,
+// nor are read-only files unless --chmod-writable is given, an error lists them once the others were written.
+// When writing a file fails the files written in place before are restored, so the tree is left as it was.
+// A journal which cannot be saved is only logged, the files are written by then.
+func applyChanges() (err error) {
+	var conflicts, readOnly []string
+	var written []journalFile
+	var originals []change
+	defer func() {
+		if len(written) == 0 {
+			return
+		}
+		if err := saveJournal(written); err != nil {
+			log.Printf("Not recording the run, -undo cannot revert it: %v", err)
+		}
+	}()
+	for _, c := range pending {
+		if outputWriter == nil && !forceStaleWrite {
+			changed, err := stale(c)
+			if err != nil {
+				return err
+			}
+			if changed {
+				conflicts = append(conflicts, c.fileName)
+				continue
+			}
+		}
+		// a new file has the mode it is created with
+		locked := outputWriter == nil && c.source != "" && c.perm&0200 == 0
+		if locked && !chmodWritable {
+			readOnly = append(readOnly, c.fileName)
+			continue
+		}
+		entry, ok, err := journalEntry(c)
+		if err != nil {
+			return err
+		}
+		var w Writer = c.fsys
+		if outputWriter != nil {
+			w = outputWriter
+		} else {
+			o, err := readOriginal(c)
+			if err != nil {
+				return err
+			}
+			originals = append(originals, o)
+		}
+		if locked {
+			err = writeReadOnly(c)
+		} else {
+			err = w.Write(c.fileName, c.data, c.perm)
+		}
+		if err != nil {
+			if outputWriter == nil {
+				// the file failing is left as it was, the rename of dirFS is atomic
+				rollback(originals[:len(originals)-1])
+				written = nil
+			}
+			return fmt.Errorf("failed writing file %s: %v", c.fileName, err)
+		}
+		statsOf(c.fileName, c.pkg).files++
+		if ok {
+			written = append(written, entry)
+		}
+	}
+	if changed := len(pending) - len(conflicts) - len(readOnly); verbose && changed > 0 {
+		log.Printf("Changed %d files", changed)
+	}
+	var problems []string
+	if len(conflicts) > 0 {
+		problems = append(problems, fmt.Sprintf("not changing %d files modified during the run, run again or use --force-stale-write:\n  %s",
+			len(conflicts), strings.Join(conflicts, "\n  ")))
+	}
+	if len(readOnly) > 0 {
+		problems = append(problems, fmt.Sprintf("%d files need changes but are read-only, use --chmod-writable to change them anyway:\n  %s",
+			len(readOnly), strings.Join(readOnly, "\n  ")))
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// readOriginal returns the change restoring the file of c as it is before c is written in place,
+// its source is empty when the file does not exist.
+func readOriginal(c change) (change, error) {
+	src, err := fs.ReadFile(c.fsys, c.fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return change{fsys: c.fsys, fileName: c.fileName, pkg: c.pkg}, nil
+	}
+	if err != nil {
+		return change{}, fmt.Errorf("failed reading file %s: %v", c.fileName, err)
+	}
+	return change{fsys: c.fsys, fileName: c.fileName, pkg: c.pkg, source: contentHash(src), data: src, perm: c.perm}, nil
+}
+
+// rollback restores the files written in place, in reverse order, and removes those created.
+// Files which cannot be restored are logged.
+func rollback(originals []change) {
+	for i := len(originals) - 1; i >= 0; i-- {
+		c := originals[i]
+		var err error
+		switch {
+		case c.source == "":
+			if fsys, ok := c.fsys.(removeFS); ok {
+				err = fsys.Remove(c.fileName)
+			} else {
+				err = fmt.Errorf("file system does not support removing files")
+			}
+		case c.perm&0200 == 0:
+			err = writeReadOnly(c)
+		default:
+			err = c.fsys.Write(c.fileName, c.data, c.perm)
+		}
+		if err != nil {
+			log.Printf("Failed restoring file %s: %v", c.fileName, err)
+		} else if verbose {
+			log.Printf("Restored file %s", c.fileName)
+		}
+	}
+}
+
+// writeReadOnly writes the read-only file of c with --chmod-writable: the file is made writable,
+// which clears the read-only attribute on Windows, written and made read-only again, also when writing fails.
+func writeReadOnly(c change) (err error) {
+	fsys, ok := c.fsys.(chmodFS)
+	if !ok {
+		return fmt.Errorf("file system does not support changing modes")
+	}
+	if err := fsys.Chmod(c.fileName, c.perm|0200); err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := fsys.Chmod(c.fileName, c.perm); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+	return fsys.Write(c.fileName, c.data, c.perm|0200)
+}
@@ -0,0 +1,135 @@
+package repair
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// failingFS is an in-memory file system failing to write the file named fail.
+type failingFS struct {
+	fstest.MapFS
+	fail string
+}
+
+func (f failingFS) Write(name string, data []byte, perm fs.FileMode) error {
+	if name == f.fail {
+		return errors.New("disk full")
+	}
+	f.MapFS[name] = &fstest.MapFile{Data: data, Mode: perm}
+	return nil
+}
+
+func (f failingFS) Remove(name string) error {
+	if _, ok := f.MapFS[name]; !ok {
+		return fs.ErrNotExist
+	}
+	delete(f.MapFS, name)
+	return nil
+}
+
+func TestApplyChangesRollsBack(t *testing.T) {
+	saved := pending
+	t.Cleanup(func() { pending = saved })
+	fsys := failingFS{MapFS: fstest.MapFS{
+		"a.go": {Data: []byte("package a\n\nfunc A() {}\n"), Mode: 0644},
+		"b.go": {Data: []byte("package a\n\nfunc B() {}\n"), Mode: 0644},
+	}, fail: "b.go"}
+	pending = nil
+	for _, name := range []string{"a.go", "c.go", "b.go"} {
+		source := ""
+		if f, ok := fsys.MapFS[name]; ok {
+			source = contentHash(f.Data)
+		}
+		stage(fsys, name, "a", source, []byte("package a\n\n// repaired\n"), 0644, nil)
+	}
+	err := applyChanges()
+	if err == nil || !strings.Contains(err.Error(), "failed writing file b.go: disk full") {
+		t.Fatalf("got error %v, want the write of b.go to fail", err)
+	}
+	if got := string(fsys.MapFS["a.go"].Data); got != "package a\n\nfunc A() {}\n" {
+		t.Errorf("a.go written before the failure was not restored: %q", got)
+	}
+	if _, ok := fsys.MapFS["c.go"]; ok {
+		t.Error("c.go created before the failure was not removed")
+	}
+	if got := string(fsys.MapFS["b.go"].Data); got != "package a\n\nfunc B() {}\n" {
+		t.Errorf("b.go failing was changed: %q", got)
+	}
+}
+
+// limitFiles are five files each needing a doc.
+var limitFiles = map[string]string{
+	"a.go": "package limit\n\nfunc A() {}\n",
+	"b.go": "package limit\n\nfunc B() {}\n",
+	"c.go": "package limit\n\nfunc C() {}\n",
+	"d.go": "package limit\n\nfunc D() {}\n",
+	"e.go": "package limit\n\nfunc E() {}\n",
+}
+
+// modifiedFiles returns the names of the files of after differing from before.
+func modifiedFiles(before, after map[string]string) []string {
+	var names []string
+	for name, content := range after {
+		if before[name] != content {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestMaxChangesTruncate(t *testing.T) {
+	dir := writeTree(t, limitFiles)
+	before := readTree(t, dir)
+	_, stderr, code := run(t, dir, "-max-changes", "2", "-max-changes-mode", "truncate")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if changed := modifiedFiles(before, readTree(t, dir)); len(changed) != 2 {
+		t.Errorf("%d files were changed, want 2: %v", len(changed), changed)
+	}
+	if !strings.Contains(stderr, "Changing 2 of 5 files, 3 are left for later runs") {
+		t.Errorf("files left are not reported: %s", stderr)
+	}
+	// the next run picks up where the last one stopped
+	run(t, dir, "-max-changes", "2", "-max-changes-mode", "truncate")
+	if changed := modifiedFiles(before, readTree(t, dir)); len(changed) != 4 {
+		t.Errorf("%d files were changed after two runs, want 4: %v", len(changed), changed)
+	}
+}
+
+func TestMaxChangesAbort(t *testing.T) {
+	dir := writeTree(t, limitFiles)
+	before := readTree(t, dir)
+	_, stderr, code := run(t, dir, "-max-changes", "2")
+	if code == 0 || !strings.Contains(stderr, "refusing to change 5 files") {
+		t.Errorf("exit status %d, stderr %q, want the run refused", code, stderr)
+	}
+	if changed := modifiedFiles(before, readTree(t, dir)); len(changed) != 0 {
+		t.Errorf("refused run changed %v", changed)
+	}
+}
+
+// A run exceeding --timeout writes nothing, files are only written once the whole tree was processed.
+func TestTimeoutNoPartialWrites(t *testing.T) {
+	_, describe := slowDescribe(t)
+	files := make(map[string]string)
+	for i := 0; i < 40; i++ {
+		for j := 0; j < 5; j++ {
+			files[fmt.Sprintf("pkg%d/f%d.go", i, j)] = fmt.Sprintf("package pkg%d\n\nfunc F%d() {}\n\ntype T%d int\n", i, j, j)
+		}
+	}
+	dir := writeTree(t, files)
+	before := readTree(t, dir)
+	_, stderr, code := run(t, dir, "-auto-description", "-describe-cmd", describe, "-timeout", "500ms")
+	if code != exitTimeout || !strings.Contains(stderr, "aborted after the timeout of 500ms, no file was changed") {
+		t.Errorf("exit status %d, stderr %q, want the run aborted by the timeout", code, stderr)
+	}
+	if after := readTree(t, dir); !reflect.DeepEqual(after, before) {
+		t.Errorf("%d files were changed or left behind", len(modifiedFiles(before, after)))
+	}
+}
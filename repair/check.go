@@ -0,0 +1,299 @@
+package repair
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// finding is an exported declaration whose godoc needs to be repaired, reported in check mode.
+type finding struct {
+	pos    token.Position
+	pkg    string
+	symbol string
+	kind   string
+	// rule is the id of the rule reporting the finding, e.g. missing-doc.
+	rule string
+	// placeholder is set when the doc is a placeholder left by this tool.
+	placeholder bool
+	// suggestion is the doc the repair would write, only set for -output csv.
+	suggestion string
+	// docName is the name a name-mismatch doc starts with.
+	docName string
+}
+
+func (f finding) String() string {
+	// missing-doc findings keep the format they had before there were other rules,
+	// errors being the default severity only warnings are marked
+	var s string
+	if f.rule == ruleMissingDoc {
+		s = fmt.Sprintf("%s: %s %s missing godoc", f.pos, f.kind, f.symbol)
+	} else {
+		s = fmt.Sprintf("%s: %s %s %s [%s]", f.pos, f.kind, f.symbol, f.message(), f.rule)
+	}
+	if severity := ruleSeverity(f.rule); severity != severityError {
+		s += " (" + severity + ")"
+	}
+	return s
+}
+
+// message describes the finding, naming the name a name-mismatch doc starts with.
+func (f finding) message() string {
+	if f.rule == ruleNameMismatch && f.docName != "" {
+		return fmt.Sprintf("godoc starts with %s instead of its name", f.docName)
+	}
+	return ruleMessage(f.rule)
+}
+
+// findings collects the findings of a check run.
+var findings []finding
+
+// allowlist holds the patterns of symbols which may remain undocumented in check mode.
+var allowlist []string
+
+// report records a finding of rule for d unless the rule is disabled or d is allowlisted,
+// findings of declarations suppressed by //nolint are recorded apart.
+func report(d declaration, rule string) {
+	symbol := d.symbol()
+	if !ruleEnabled(rule) || allowed(d.pkg, symbol) {
+		return
+	}
+	f := finding{pos: d.pos, pkg: d.pkg, symbol: symbol, kind: d.kind, rule: rule, placeholder: d.placeholder, docName: d.docName}
+	if d.nolint {
+		suppressed = append(suppressed, f)
+		return
+	}
+	findings = append(findings, f)
+}
+
+// allowed reports whether symbol of package pkg matches a pattern of the allowlist.
+// Patterns use path.Match syntax and are matched against both the symbol, e.g. "Client.Close",
+// and the symbol qualified by its package name, e.g. "store.Client.Close".
+func allowed(pkg, symbol string) bool {
+	for _, pattern := range allowlist {
+		if ok, _ := path.Match(pattern, symbol); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, pkg+"."+symbol); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// loadAllowlist reads the allowlist patterns from fileName, one per line.
+// Empty lines and lines starting with # are ignored.
+func loadAllowlist(fileName string) ([]string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening allowlist %s: %v", fileName, err)
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowlist pattern %q: %v", line, err)
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading allowlist %s: %v", fileName, err)
+	}
+	return patterns, nil
+}
+
+// printFindings writes the findings ordered by position to out.
+func printFindings(out io.Writer) {
+	sortFindings()
+	for _, f := range findings {
+		fmt.Fprintln(out, f)
+	}
+}
+
+// sortFindings sorts the findings by file and position.
+func sortFindings() {
+	sortByPosition(findings)
+}
+
+// sortByPosition sorts list by file and position, the findings of a declaration by rule.
+func sortByPosition(list []finding) {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].pos, list[j].pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return list[i].rule < list[j].rule
+	})
+}
+
+// checkFile reports the declarations of file whose godoc needs to be repaired, and the package
+// comment when pkgDoc is set. It works on the ast directly, as nothing is written in check mode
+// the file is not converted to dst, which keeps checking large trees fast.
+// The doc of a declaration is the comment group directly above it, as for the decorations in autoDecl.
+func checkFile(fset *token.FileSet, file *ast.File, pkgDoc bool) {
+	pkg := file.Name.Name
+	nolint := newNolintScope(fset, file)
+	newDecl := func(name, kind string, n ast.Node) declaration {
+		return declaration{name: name, kind: kind, pkg: pkg, pos: position(fset, n.Pos()), nolint: nolint.file}
+	}
+	if pkgDoc {
+		report(newDecl(pkg, kindPackage, file), ruleMissingDoc)
+	}
+	if file.Doc != nil && len(file.Doc.List) == 1 &&
+		file.Doc.List[0].Text == fmt.Sprintf(catalog[msgPackage], pkg) {
+		report(newDecl(pkg, kindPackage, file), rulePlaceholderDoc)
+	}
+	if packageOnly {
+		return
+	}
+
+	eachDecl(fset, file, checkDecl)
+}
+
+// eachDecl calls fn with every declaration of file and its doc, in source order.
+// Functions run by go test are left out of test files.
+// Declarations are marked as suppressed by //nolint comments in their doc, on their line or on the package clause.
+func eachDecl(fset *token.FileSet, file *ast.File, fn func(declaration, *ast.CommentGroup)) {
+	pkg := file.Name.Name
+	nolint := newNolintScope(fset, file)
+	newDecl := func(name, kind string, n ast.Node) declaration {
+		return declaration{name: name, kind: kind, pkg: pkg, pos: position(fset, n.Pos()), nolint: nolint.covers(fset, n.Pos())}
+	}
+	visit := fn
+	fn = func(d declaration, doc *ast.CommentGroup) {
+		if doc != nil {
+			for _, c := range doc.List {
+				d.nolint = d.nolint || suppresses(c.Text)
+			}
+		}
+		visit(d, doc)
+	}
+	testFile := strings.HasSuffix(fset.File(file.Pos()).Name(), "_test.go")
+	for _, decl := range file.Decls {
+		switch t := decl.(type) {
+		case *ast.FuncDecl:
+			params, results := astFieldTypes(t.Type.Params), astFieldTypes(t.Type.Results)
+			if testFile && isTestingFunc(t.Name.Name, t.Recv != nil, params, results) {
+				continue
+			}
+			d := newDecl(t.Name.Name, kindFunc, t)
+			d.results = results
+			if t.Recv != nil {
+				d.kind = kindMethod
+				d.recvType = recvTypeName(t.Recv)
+				d.receiver = types.ExprString(t.Recv.List[0].Type)
+			}
+			fn(d, t.Doc)
+		case *ast.GenDecl:
+			kind := genDeclKind(t.Tok)
+			for _, spec := range t.Specs {
+				doc := t.Doc
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if len(t.Specs) > 1 {
+						doc = s.Doc
+					}
+					fn(newDecl(s.Name.Name, kind, s), doc)
+					eachField(s, newDecl, fn)
+				case *ast.ValueSpec:
+					if len(t.Specs) > 1 {
+						doc = s.Doc
+					}
+					fn(newDecl(astSpecName(s.Names), kind, s), doc)
+				}
+			}
+		}
+	}
+}
+
+// astSpecName is specName for the names of an ast value spec.
+func astSpecName(names []*ast.Ident) string {
+	for _, name := range names {
+		if name.IsExported() {
+			return name.Name
+		}
+	}
+	return names[0].Name
+}
+
+// position returns the physical position of pos in the file as it is on disk,
+// or with --line-directives the position adjusted by //line directives.
+func position(fset *token.FileSet, pos token.Pos) token.Position {
+	return fset.PositionFor(pos, lineDirectives)
+}
+
+// checkDecl reports d if its doc would be repaired by autoDecl,
+// or with --treat-placeholders-as-missing if its doc is a lone placeholder.
+func checkDecl(d declaration, doc *ast.CommentGroup) {
+	if !selected(d) || skipDecl(d) {
+		return
+	}
+	var decs []string
+	if doc != nil {
+		for _, c := range doc.List {
+			decs = append(decs, c.Text)
+		}
+	}
+	lead := leadingDirectives(decs)
+	lines, _ := lineComments(decs[lead:])
+	decs = append(decs[:lead:lead], lines...)
+	state := Classify(decs, d.name)
+	before := len(findings)
+	defer func() { countDecl(d, len(findings) > before) }()
+	if requireTypeOverview {
+		recordOverview(d, state.missing())
+	}
+	d.placeholder = len(decs) == lead+1 && isPlaceholder(decs[lead], d)
+	if state == WrongPrefix && ruleEnabled(ruleNameMismatch) {
+		d.docName, _ = staleName(decs[lead], d.name)
+	}
+	switch {
+	case state.missing() || state == Deprecated:
+		report(d, ruleMissingDoc)
+	case state == JustName && ruleEnabled(ruleNonTrivial):
+		report(d, ruleNonTrivial)
+	case d.docName != "":
+		report(d, ruleNameMismatch)
+	case state == WrongPrefix && ruleEnabled(ruleStartWithName):
+		if !startsWithArticle(decs[lead], d.name) {
+			report(d, ruleStartWithName)
+		}
+	case state == WrongPrefix || state == JustName && justNamePolicy != policyKeep:
+		report(d, ruleMissingDoc)
+	case d.placeholder:
+		report(d, rulePlaceholderDoc)
+	}
+	if !state.missing() && state != JustName && !endsWithPunctuation(decs[lead:]) {
+		report(d, ruleEndsWithPeriod)
+	}
+}
+
+// astFieldTypes returns the type of each field of fields, as fieldTypes does for dst.
+func astFieldTypes(fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var list []string
+	for _, field := range fields.List {
+		for i := 0; i < len(field.Names) || i == 0; i++ {
+			list = append(list, types.ExprString(field.Type))
+		}
+	}
+	return list
+}
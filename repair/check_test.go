@@ -0,0 +1,133 @@
+package repair
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckQuietSuccess(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.go": "// Package quiet is documented.\npackage quiet\n\n// Open opens.\nfunc Open() {}\n",
+	})
+	stdout, stderr, code := run(t, dir, "-check", "-quiet-success")
+	if code != 0 || stdout != "" || stderr != "" {
+		t.Errorf("exit status %d, stdout %q, stderr %q, want no output on a clean tree", code, stdout, stderr)
+	}
+
+	dir = writeTree(t, map[string]string{"a.go": "package quiet\n\nfunc Open() {}\n"})
+	stdout, stderr, code = run(t, dir, "-check", "-quiet-success")
+	if code != 1 || stderr != "" {
+		t.Errorf("exit status %d, stderr %q, want 1 and only the findings", code, stderr)
+	}
+	if want := "a.go:3:1: func Open missing godoc\n"; stdout != want {
+		t.Errorf("stdout %q, want the finding %q", stdout, want)
+	}
+}
+
+// benchmarkSource returns a large package source, n types each with a method, half of them documented.
+func benchmarkSource(n int) []byte {
+	var b strings.Builder
+	b.WriteString("package bench\n")
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			fmt.Fprintf(&b, "\n// T%d is a type.\n", i)
+		}
+		fmt.Fprintf(&b, "type T%d struct {\n\tName string // the name\n}\n\n", i)
+		fmt.Fprintf(&b, "func (t *T%[1]d) Get(key string) (string, error) {\n\t// look it up\n\treturn key, nil\n}\n", i)
+	}
+	return []byte(b.String())
+}
+
+// benchmarkFile parses the source of benchmarkSource and configures the run with the default options.
+func benchmarkFile(b *testing.B) (*token.FileSet, *ast.File) {
+	b.Helper()
+	if err := configure(Options{}); err != nil {
+		b.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "bench.go", benchmarkSource(1000), parser.ParseComments)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return fset, file
+}
+
+// BenchmarkCheckFile measures the ast path of --check, compare with BenchmarkInstrumentFile.
+func BenchmarkCheckFile(b *testing.B) {
+	fset, file := benchmarkFile(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		findings = findings[:0]
+		checkFile(fset, file, false)
+	}
+}
+
+// BenchmarkInstrumentFile measures the dst path repairing a file, which --check used before.
+func BenchmarkInstrumentFile(b *testing.B) {
+	fset, file := benchmarkFile(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fileEdits = fileEdits[:0]
+		if err := instrumentFile(context.Background(), fset, file, false, io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestAllowUndocumented(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.go":        "package store\n\nfunc Open() {}\n\nfunc Close() {}\n\ntype Client struct{}\n\nfunc (c *Client) Get() {}\n\nfunc (c *Client) Put() {}\n",
+		"legacy/b.go": "package legacy\n\nfunc Old() {}\n\nfunc Older() {}\n",
+	})
+	allowlist := filepath.Join(t.TempDir(), "allow.txt")
+	if err := os.WriteFile(allowlist, []byte("# reviewed\nOpen\nClient.Get\n\nlegacy.*\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, code := run(t, dir, "-check", "-quiet-success", "-allow-undocumented", allowlist)
+	want := "a.go:5:1: func Close missing godoc\na.go:7:6: type Client missing godoc\na.go:11:1: method Client.Put missing godoc\n"
+	if code != 1 || stdout != want {
+		t.Errorf("exit status %d, findings %q, want %q", code, stdout, want)
+	}
+	if err := os.WriteFile(allowlist, []byte("Open[\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, code := run(t, dir, "-check", "-allow-undocumented", allowlist); code == 0 || !strings.Contains(stderr, `invalid allowlist pattern "Open["`) {
+		t.Errorf("exit status %d, stderr %q, want the pattern rejected", code, stderr)
+	}
+}
+
+func TestRequireTypeOverview(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"store.go": "package p\n\n// Store stores.\ntype Store struct{}\n\n// Get gets.\nfunc (s *Store) Get() {}\n\nfunc (s *Store) Put() {}\n\n" +
+			"// Full is full.\ntype Full struct{}\n\n// Do does.\nfunc (Full) Do() {}\n\ntype Bare struct{}\n\nfunc (Bare) X() {}\n",
+		// methods of the package's other files count as well
+		"store_len.go": "package p\n\nfunc (s Store) Len() int { return 0 }\n",
+	})
+	stdout, _, code := run(t, dir, "-check", "-quiet-success", "-require-type-overview")
+	if code != 1 {
+		t.Errorf("exit status %d, want 1", code)
+	}
+	want := "store.go:4:6: type Store is partially documented, 2 of 3 methods have no doc:\n" +
+		"\tstore.go:9:1: method Store.Put\n" +
+		"\tstore_len.go:3:1: method Store.Len\n"
+	if !strings.Contains(stdout, want) {
+		t.Errorf("output lacks %q:\n%s", want, stdout)
+	}
+	// types documented with all their methods and undocumented types are not partially documented
+	for _, name := range []string{"type Full", "type Bare"} {
+		if strings.Contains(stdout, name+" is partially documented") {
+			t.Errorf("%s is listed:\n%s", name, stdout)
+		}
+	}
+	if stdout, _, _ := run(t, dir, "-check", "-quiet-success"); strings.Contains(stdout, "partially documented") {
+		t.Errorf("types listed without -require-type-overview:\n%s", stdout)
+	}
+}
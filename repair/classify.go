@@ -0,0 +1,72 @@
+package repair
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DocState classifies the doc of a declaration, see Classify.
+type DocState int
+
+// States of the doc of a declaration.
+const (
+	// Missing is a declaration without doc.
+	Missing DocState = iota
+	// Directive is a doc holding only directives such as //go:generate, which go/doc leaves out.
+	Directive
+	// JustName is a doc holding only the name, e.g. "// GetUser".
+	JustName
+	// WrongPrefix is a doc not starting with the name.
+	WrongPrefix
+	// Deprecated is a doc starting with a Deprecated paragraph, which needs a summary above it.
+	Deprecated
+	// OK is a doc starting with the name.
+	OK
+	// Placeholder is a doc holding only the placeholder this tool inserts for a missing doc,
+	// e.g. "// GetUser missing godoc.", with the default comment format or that of the language of the run.
+	Placeholder
+)
+
+// stateNames are the names of the states, as printed by String.
+var stateNames = [...]string{"Missing", "Directive", "JustName", "WrongPrefix", "Deprecated", "OK", "Placeholder"}
+
+func (s DocState) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return fmt.Sprintf("DocState(%d)", int(s))
+	}
+	return stateNames[s]
+}
+
+// missing reports whether s is a doc go/doc shows nothing for.
+func (s DocState) missing() bool {
+	return s == Missing || s == Directive
+}
+
+// Classify returns the state of decs, the line comments of the doc of the declaration name such as
+// "// GetUser returns the user.", leading directives included. Block comments are to be given as line comments.
+func Classify(decs []string, name string) DocState {
+	lead := leadingDirectives(decs)
+	switch {
+	case len(decs) == 0:
+		return Missing
+	case lead == len(decs):
+		return Directive
+	}
+	first := decs[lead]
+	switch {
+	case first == fmt.Sprintf("// %s", name) || first == fmt.Sprintf("//%s", name):
+		return JustName
+	case strings.HasPrefix(first, "// Deprecated: "):
+		return Deprecated
+	case !strings.HasPrefix(first, fmt.Sprintf("// %s ", name)):
+		return WrongPrefix
+	}
+	if _, ok := afterNameSeparator(first, name); ok {
+		return WrongPrefix
+	}
+	if len(decs) == lead+1 && (first == fmt.Sprintf(defaultCommentFormat, name) ||
+		catalog != nil && first == fmt.Sprintf(catalog[msgPlaceholder], name)) {
+		return Placeholder
+	}
+	return OK
+}
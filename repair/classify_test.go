@@ -0,0 +1,37 @@
+package repair
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		decs []string
+		want DocState
+	}{
+		{nil, Missing},
+		{[]string{"//go:generate stringer -type Level"}, Directive},
+		{[]string{"// GetUser"}, JustName},
+		{[]string{"// Returns the user of id."}, WrongPrefix},
+		{[]string{"// Deprecated: use FindUser instead."}, Deprecated},
+		{[]string{"// GetUser returns the user of id."}, OK},
+		{[]string{"// GetUser missing godoc."}, Placeholder},
+		// directives are skipped before the doc is classified
+		{[]string{"//go:noinline", "// GetUser returns the user of id."}, OK},
+		{[]string{"//nolint:errcheck", "//GetUser"}, JustName},
+		// the name followed by a separator is not the start of a sentence
+		{[]string{"// GetUser: returns the user of id."}, WrongPrefix},
+		// a placeholder completed by the author is a doc
+		{[]string{"// GetUser missing godoc.", "// It returns the user of id."}, OK},
+	}
+	seen := make(map[DocState]bool)
+	for _, tt := range tests {
+		if got := Classify(tt.decs, "GetUser"); got != tt.want {
+			t.Errorf("Classify(%q) = %v, want %v", tt.decs, got, tt.want)
+		}
+		seen[tt.want] = true
+	}
+	for s := Missing; s <= Placeholder; s++ {
+		if !seen[s] {
+			t.Errorf("no test of state %v", s)
+		}
+	}
+}
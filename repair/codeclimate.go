@@ -0,0 +1,54 @@
+package repair
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+)
+
+// outputCodeClimate is the -output of -check printing the findings as a Code Climate report,
+// the format of the GitLab code quality widget.
+const outputCodeClimate = "codeclimate"
+
+// codeClimateIssue is an issue of a Code Climate report.
+type codeClimateIssue struct {
+	Description string `json:"description"`
+	CheckName   string `json:"check_name"`
+	// Fingerprint identifies the issue across runs, it does not depend on line numbers
+	// so moving code does not make an issue new.
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeClimateLocation `json:"location"`
+}
+
+type codeClimateLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+// codeClimateSeverities maps the severities of rules to those of Code Climate.
+var codeClimateSeverities = map[string]string{
+	severityError:   "major",
+	severityWarning: "minor",
+}
+
+// printCodeClimate prints the findings as a single JSON array of Code Climate issues.
+func printCodeClimate(out io.Writer) error {
+	sortFindings()
+	issues := []codeClimateIssue{}
+	for _, f := range findings {
+		issue := codeClimateIssue{
+			Description: f.kind + " " + f.symbol + " " + f.message(),
+			CheckName:   f.rule,
+			Fingerprint: contentHash([]byte(strings.Join([]string{path.Dir(f.pos.Filename), f.pkg, f.symbol, f.kind, f.rule}, "\n"))),
+			Severity:    codeClimateSeverities[ruleSeverity(f.rule)],
+		}
+		issue.Location.Path = f.pos.Filename
+		issue.Location.Lines.Begin = f.pos.Line
+		issues = append(issues, issue)
+	}
+	return json.NewEncoder(out).Encode(issues)
+}
@@ -0,0 +1,101 @@
+package repair
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// compareRefs returns the findings of the .go files of dir changed between the revisions of base..head
+// which are new in head: declarations which did not exist in base or were documented there.
+// Renamed files are followed as detected by git, files are read with git show so nothing is checked out.
+func compareRefs(dir, refs string) ([]finding, error) {
+	base, head, ok := strings.Cut(refs, "..")
+	if !ok || base == "" || head == "" || strings.HasPrefix(head, ".") {
+		return nil, fmt.Errorf("invalid range %q, expected base..head", refs)
+	}
+	out, err := git(dir, "diff", "--name-status", "-M", "--relative", "--diff-filter=AMR", base, head, "--", "*.go")
+	if err != nil {
+		return nil, fmt.Errorf("failed listing files changed between %s and %s: %v", base, head, err)
+	}
+	undocumented := make(map[string]bool)
+	var offenders []finding
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		// renames list the path in base first, additions have none
+		headFile, baseFile := fields[len(fields)-1], fields[1]
+		if fields[0] == "A" {
+			baseFile = ""
+		}
+		if !includeTests && strings.HasSuffix(headFile, "_test.go") {
+			continue
+		}
+		headFindings, err := checkRevision(dir, head, headFile, headFile)
+		if err != nil {
+			return nil, err
+		}
+		offenders = append(offenders, headFindings...)
+		if baseFile == "" {
+			continue
+		}
+		baseFindings, err := checkRevision(dir, base, baseFile, headFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range baseFindings {
+			undocumented[compareKey(f)] = true
+		}
+	}
+	findings = nil
+	for _, f := range offenders {
+		if !undocumented[compareKey(f)] {
+			findings = append(findings, f)
+		}
+	}
+	sortFindings()
+	return findings, nil
+}
+
+// compareKey identifies the declaration of f in both revisions, by the directory of its file in head.
+func compareKey(f finding) string {
+	return path.Dir(f.pos.Filename) + ":" + f.pkg + "." + f.kind + " " + f.symbol
+}
+
+// checkRevision returns the findings of fileName at the git revision rev, reported as positions of name.
+// Generated files have no findings.
+func checkRevision(dir, rev, fileName, name string) ([]finding, error) {
+	src, err := git(dir, "show", rev+":./"+fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading %s at %s: %v", fileName, rev, err)
+	}
+	if firstLine, _, _ := strings.Cut(string(src), "\n"); strings.Contains(path.Base(name), "generated") || generatedLine(firstLine) {
+		return nil, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing %s at %s: %v", fileName, rev, err)
+	}
+	findings = nil
+	checkFile(fset, file, false)
+	return findings, nil
+}
+
+// git runs git in dir with args and returns its output, the error holds what git printed on stderr.
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
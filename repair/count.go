@@ -0,0 +1,39 @@
+package repair
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+)
+
+// countBy maps the groupings of -count-by to the name of the group of a finding.
+var countBy = map[string]func(f finding) string{
+	"kind": func(f finding) string { return f.kind },
+	"package": func(f finding) string {
+		return packagePath(path.Dir(f.pos.Filename), f.pkg)
+	},
+	"rule": func(f finding) string { return f.rule },
+}
+
+// printCount prints the number of findings, or with a grouping of -count-by a name<TAB>count line
+// for each of its groups, sorted by name.
+func printCount(out io.Writer, by string) {
+	group, ok := countBy[by]
+	if !ok {
+		fmt.Fprintln(out, len(findings))
+		return
+	}
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[group(f)]++
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "%s\t%d\n", name, counts[name])
+	}
+}
@@ -0,0 +1,36 @@
+package repair
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCountMatchesCheck(t *testing.T) {
+	dir := copyExample(t, exampleFiles...)
+	for _, args := range [][]string{nil, {"-strict", "all"}, {"-only-kinds", "func,method"}} {
+		stdout, _, _ := run(t, dir, append([]string{"-check", "-quiet-success"}, args...)...)
+		findings := strings.Count(stdout, "\n")
+		stdout, _, _ = run(t, dir, append([]string{"-count"}, args...)...)
+		count, err := strconv.Atoi(strings.TrimSpace(stdout))
+		if err != nil {
+			t.Fatalf("%v: count %q is not a number", args, stdout)
+		}
+		if count != findings || count == 0 {
+			t.Errorf("%v: count %d, want the %d findings of -check", args, count, findings)
+		}
+		// the counts grouped by kind add up to the same
+		stdout, _, _ = run(t, dir, append([]string{"-count-by", "kind"}, args...)...)
+		sum := 0
+		for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+			n, err := strconv.Atoi(line[strings.LastIndex(line, "\t")+1:])
+			if err != nil {
+				t.Fatalf("%v: count line %q", args, line)
+			}
+			sum += n
+		}
+		if sum != findings {
+			t.Errorf("%v: counts by kind add up to %d, want %d", args, sum, findings)
+		}
+	}
+}
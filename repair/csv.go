@@ -0,0 +1,59 @@
+package repair
+
+import (
+	"context"
+	"encoding/csv"
+	"go/ast"
+	"go/token"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// outputCSV is the -output of -check printing a CSV row for each finding.
+const outputCSV = "csv"
+
+// suggestComments sets the suggestion of the findings of file to the doc the repair would write,
+// by running it on the file without writing anything.
+func suggestComments(ctx context.Context, fset *token.FileSet, file *ast.File, pkgDoc bool, list []finding) error {
+	// the dry run must not count the declarations again
+	saved := stats
+	stats = make(map[string]*packageStats)
+	fileEdits = nil
+	defer func() {
+		stats = saved
+		fileEdits = nil
+	}()
+	if err := instrumentFile(ctx, fset, file, pkgDoc, io.Discard); err != nil {
+		return err
+	}
+	texts := make(map[string]string)
+	for _, e := range fileEdits {
+		texts[e.Kind+" "+e.Symbol] = e.Text
+	}
+	for i := range list {
+		list[i].suggestion = texts[list[i].kind+" "+list[i].symbol]
+	}
+	return nil
+}
+
+// printCSV prints the findings as CSV with a header row, sorted by package, file and line.
+func printCSV(out io.Writer) error {
+	sortFindings()
+	list := append([]finding(nil), findings...)
+	pkgPath := func(f finding) string {
+		return packagePath(path.Dir(f.pos.Filename), f.pkg)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return pkgPath(list[i]) < pkgPath(list[j])
+	})
+	w := csv.NewWriter(out)
+	w.Write([]string{"package", "file", "line", "symbol", "kind", "rule", "severity", "has_placeholder", "suggested_comment"})
+	for _, f := range list {
+		w.Write([]string{pkgPath(f), f.pos.Filename, strconv.Itoa(f.pos.Line), f.symbol, f.kind, f.rule,
+			ruleSeverity(f.rule), strconv.FormatBool(f.placeholder), f.suggestion})
+	}
+	w.Flush()
+	return w.Error()
+}
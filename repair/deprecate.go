@@ -0,0 +1,93 @@
+package repair
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dave/dst"
+	"gopkg.in/yaml.v3"
+)
+
+// deprecations maps symbols qualified by their package name, e.g. "store.Client.Get",
+// to the notice of their Deprecated paragraph, nil unless --deprecate is given.
+var deprecations map[string]string
+
+// deprecated records the symbols of deprecations which were found.
+var deprecated = make(map[string]bool)
+
+// loadDeprecations reads a YAML file mapping pkg.Symbol to a notice such as "use GetContext instead.",
+// a single word is taken as the replacement, e.g. "GetContext".
+func loadDeprecations(fileName string) (map[string]string, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading deprecations %s: %v", fileName, err)
+	}
+	var m map[string]string
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed parsing deprecations %s: %v", fileName, err)
+	}
+	for symbol, notice := range m {
+		notice = strings.TrimSpace(notice)
+		if notice == "" {
+			return nil, fmt.Errorf("empty deprecation notice for %q", symbol)
+		}
+		if !strings.ContainsAny(notice, " \t") {
+			notice = fmt.Sprintf("use %s instead.", notice)
+		}
+		m[symbol] = notice
+	}
+	if m == nil {
+		m = make(map[string]string)
+	}
+	return m, nil
+}
+
+// deprecate appends the Deprecated paragraph of d to its doc, or makes it the whole doc
+// when there is none. A doc which already has a Deprecated paragraph is left as it is.
+func deprecate(d declaration, decorations dst.Decorations) dst.Decorations {
+	symbol := d.pkg + "." + d.symbol()
+	notice, ok := deprecations[symbol]
+	if !ok {
+		return decorations
+	}
+	deprecated[symbol] = true
+	all := decorations.All()
+	split := attachedStart(all)
+	detached, attached := all[:split:split], all[split:]
+	lead := leadingDirectives(attached)
+	for _, comment := range attached[lead:] {
+		if strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(comment, "//")), "Deprecated: ") {
+			return decorations
+		}
+	}
+	lines := wrapComment("// Deprecated: "+notice, wrapWidth)
+	// the paragraph goes at the end of the doc, before directives following it such as //go:noinline
+	end := len(attached)
+	for end > lead && isDirective(attached[end-1]) {
+		end--
+	}
+	if end == lead {
+		attached = append(lines, attached...)
+		recordEdit(d, actionInserted, lines, nil)
+	} else {
+		lines = append([]string{"//"}, lines...)
+		attached = append(append(attached[:end:end], lines...), attached[end:]...)
+		recordEdit(d, actionRewritten, attached[lead:end+len(lines)], nil)
+	}
+	decorations.Replace(append(detached, attached...)...)
+	return decorations
+}
+
+// missingDeprecations returns the symbols of deprecations which were not found.
+func missingDeprecations() []string {
+	var symbols []string
+	for symbol := range deprecations {
+		if !deprecated[symbol] {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
@@ -0,0 +1,637 @@
+package repair
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dave/dst"
+)
+
+// declaration holds what is known about an exported identifier being documented.
+type declaration struct {
+	name string
+	kind string
+	// pkg is the name of the package the declaration belongs to.
+	pkg string
+	pos token.Position
+	// recvType is the receiver type name of a method without pointer or type parameters, e.g. "Stack".
+	recvType string
+	// receiver is the receiver type of a method as written in source, e.g. "*Stack[T]".
+	receiver string
+	// results are the result types of a function as written in source, one per result.
+	results []string
+	// fn is the signature of a function declaration, only set when the file is repaired.
+	fn *dst.FuncType
+	// spec is the spec of a type declaration, only set when the file is repaired.
+	spec *dst.TypeSpec
+	// value is the value of a var declaring a single name, only set when the file is repaired.
+	value dst.Expr
+	// tag is the tag of a struct field as written in source, e.g. `yaml:"port"`.
+	tag string
+	// callback is set for vars of function type, e.g. var OnError func(error).
+	callback bool
+	// placeholder is set in check mode when the doc is a placeholder left by this tool.
+	placeholder bool
+	// docName is set in check mode to the name the doc starts with when it is not the name of the declaration.
+	docName string
+	// nolint is set when a //nolint comment suppresses the findings and fixes of the declaration.
+	nolint bool
+}
+
+// symbol returns the name of d as referred to within its package, e.g. "Client.Close" for methods
+// or "Config.Port" for fields.
+func (d declaration) symbol() string {
+	if d.kind == kindMethod || d.kind == kindField {
+		return d.recvType + "." + d.name
+	}
+	return d.name
+}
+
+// description is the auto description of a declaration.
+type description struct {
+	// summary follows the name on the first line, e.g. "is a user service."
+	summary string
+	// clauses are fragments derived from the signature, e.g. "accepts a variable number of string values".
+	clauses []string
+	// sentences are complete sentences following the summary.
+	sentences []string
+}
+
+// String returns the description on a single line, clauses are joined to the summary with "; ".
+func (desc description) String() string {
+	s := desc.summary
+	for _, clause := range desc.clauses {
+		s += "; " + clause
+	}
+	for _, sentence := range desc.sentences {
+		s = withSentence(s, sentence)
+	}
+	return s
+}
+
+// paragraph returns everything but the summary as sentences, empty if there is nothing more to say.
+func (desc description) paragraph() string {
+	var sentences []string
+	for _, clause := range desc.clauses {
+		r, size := utf8.DecodeRuneInString(clause)
+		sentences = append(sentences, terminate(string(unicode.ToUpper(r))+clause[size:]))
+	}
+	return strings.Join(append(sentences, desc.sentences...), " ")
+}
+
+// describe builds the auto description of d, e.g. "UserService" -> "is a user service."
+// when articles are enabled for types, otherwise the bare words of mockDoc.
+// A first word repeating the package name is left out, see stripPackage.
+// Functions get clauses derived from their signature. Their summary is the first of: the error action
+// of methods returning only an error, "returns a new X" for constructors NewX, the phrase of the first word,
+// the conjugated words.
+func describe(d declaration) description {
+	if !wordSplit {
+		return description{summary: catalog[msgDescription]}
+	}
+	if d.spec != nil {
+		if summary := typeSource(d.spec); summary != "" {
+			return description{summary: summary}
+		}
+	}
+	if ref := reference(d.value); ref != "" {
+		return description{summary: fmt.Sprintf(catalog[msgValueAlias], ref)}
+	}
+	words := stripPackage(nameWords(d.name), d.pkg)
+	if d.callback {
+		return description{summary: callbackPhrase(words)}
+	}
+	if d.fn != nil {
+		desc := description{summary: phrase(words), clauses: signatureClauses(d.fn)}
+		if action := errorAction(d); action != "" {
+			desc.summary = action
+		} else if isConstructor(d) {
+			desc.summary = fmt.Sprintf(catalog[msgConstructor], strings.TrimPrefix(d.results[0], "*"))
+		} else if names := resultNames(d.fn); mentionResults && len(names) > 0 {
+			desc.summary = fmt.Sprintf(catalog[msgResults], desc.summary, joinWords(names))
+		}
+		if sentence := contextSentence(d); sentence != "" {
+			desc.sentences = append(desc.sentences, sentence)
+		}
+		return desc
+	}
+	if d.kind == kindField {
+		// a field named by a single word reads as a noun, e.g. "is the port." for Port
+		if words == d.name && !isAcronym(words) {
+			words = strings.ToLower(words)
+		}
+		return description{summary: fmt.Sprintf(catalog[msgField], words+tagHint(d.tag))}
+	}
+	// a name kept as written, such as DTO, would be described by itself
+	if !articles || d.kind != kindType || words == d.name {
+		return description{summary: words}
+	}
+	if isPlural(words) {
+		return description{summary: fmt.Sprintf(catalog[msgTypePlural], words)}
+	}
+	if article(words) == "an" {
+		return description{summary: fmt.Sprintf(catalog[msgTypeVowel], words)}
+	}
+	return description{summary: fmt.Sprintf(catalog[msgType], words)}
+}
+
+// nameWords returns the words of name in lower case as mockDoc does, except references to other
+// exported names of the package, which keep their casing, e.g. "convert user to DTO" if DTO is a type.
+// Acronyms are kept as written too, e.g. "parse JSON" for ParseJSON, as is a name of a single word, digits
+// staying with the word before them: "Server2" is no English word and describes nothing, unlike "server 2".
+// With --doc-links they become doc links as do imported package names, e.g. "convert user to [DTO]".
+func nameWords(name string) string {
+	var words []string
+	for _, word := range Split(name) {
+		if r, _ := utf8.DecodeRuneInString(word); unicode.IsDigit(r) && len(words) > 0 {
+			words[len(words)-1] += word
+			continue
+		}
+		words = append(words, word)
+	}
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		switch {
+		case word != name && symbols.names[word]:
+			if docLinks {
+				words[i] = "[" + word + "]"
+			}
+		case docLinks && symbols.imports[lower]:
+			words[i] = "[" + lower + "]"
+		case isAcronym(word) || word == name:
+		default:
+			words[i] = lower
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// isAcronym reports whether word is written in capitals, such as "DTO" or "SHA256".
+func isAcronym(word string) bool {
+	letters := 0
+	for _, r := range word {
+		switch {
+		case isUpper(r):
+			letters++
+		case !unicode.IsDigit(r):
+			return false
+		}
+	}
+	return letters > 1
+}
+
+// typeSource describes a type declared from another named type by referring to it,
+// e.g. "is an alias for internal.Options; see that type for details." or "is a uuid.UUID.".
+// Slices, maps and pointers of named types name their structure, e.g. "is a slice of User.".
+// Constraint interfaces name the types they permit, e.g. "is a constraint permitting ~int | ~float64.".
+// It returns an empty string for new structures and basic types, which are described by their name.
+func typeSource(spec *dst.TypeSpec) string {
+	if spec.Assign {
+		if name := namedType(spec.Type); name != "" {
+			return fmt.Sprintf(catalog[msgAlias], name)
+		}
+		return ""
+	}
+	if typeSet, ok := constraintTypes(spec); ok {
+		return fmt.Sprintf(catalog[msgConstraint], typeSet)
+	}
+	switch t := spec.Type.(type) {
+	case *dst.ArrayType:
+		if elem := namedType(t.Elt); elem != "" && t.Len == nil {
+			return fmt.Sprintf(catalog[msgSlice], elem)
+		}
+	case *dst.MapType:
+		value := namedType(t.Value)
+		if star, ok := t.Value.(*dst.StarExpr); ok && namedType(star.X) != "" {
+			value = "*" + namedType(star.X)
+		}
+		if value != "" {
+			return fmt.Sprintf(catalog[msgMap], exprString(t.Key), value)
+		}
+	case *dst.StarExpr:
+		if elem := namedType(t.X); elem != "" {
+			return fmt.Sprintf(catalog[msgPointer], elem)
+		}
+	default:
+		if name := namedType(t); name != "" {
+			if article(name) == "an" {
+				return fmt.Sprintf(catalog[msgTypeVowel], name)
+			}
+			return fmt.Sprintf(catalog[msgType], name)
+		}
+	}
+	return ""
+}
+
+// constraintTypes returns the type set of spec as written, e.g. "~int | ~float64", if it is an interface
+// holding type elements and no methods, which can only be used as a constraint.
+// Embedded interfaces cannot be told from type elements by name, so only unions, ~T, type literals,
+// predeclared types other than interfaces such as any and error, and comparable make an interface a constraint.
+func constraintTypes(spec *dst.TypeSpec) (string, bool) {
+	iface, ok := spec.Type.(*dst.InterfaceType)
+	if !ok || iface.Methods == nil {
+		return "", false
+	}
+	var elems []string
+	constraint := false
+	for _, field := range iface.Methods.List {
+		if len(field.Names) > 0 {
+			return "", false
+		}
+		switch t := field.Type.(type) {
+		case *dst.BinaryExpr, *dst.UnaryExpr, *dst.StarExpr, *dst.ArrayType, *dst.MapType, *dst.ChanType, *dst.FuncType, *dst.StructType:
+			constraint = true
+		case *dst.Ident:
+			if obj, ok := types.Universe.Lookup(t.Name).(*types.TypeName); ok {
+				constraint = constraint || t.Name == "comparable" || !types.IsInterface(obj.Type())
+			}
+		}
+		elems = append(elems, typeElem(field.Type))
+	}
+	if !constraint {
+		return "", false
+	}
+	return strings.Join(elems, catalog[msgAnd]), true
+}
+
+// typeElem returns the type element expr as written, e.g. "~int | ~float64".
+func typeElem(expr dst.Expr) string {
+	switch t := expr.(type) {
+	case *dst.BinaryExpr:
+		return typeElem(t.X) + " " + t.Op.String() + " " + typeElem(t.Y)
+	case *dst.UnaryExpr:
+		return t.Op.String() + typeElem(t.X)
+	}
+	return exprString(expr)
+}
+
+// namedType returns the name of expr if it refers to a named type other than a predeclared one,
+// e.g. "User" or "uuid.UUID", as a doc link with --doc-links. Otherwise it returns an empty string.
+func namedType(expr dst.Expr) string {
+	var name string
+	switch t := expr.(type) {
+	case *dst.Ident:
+		if types.Universe.Lookup(t.Name) != nil {
+			return ""
+		}
+		name = t.Name
+	case *dst.SelectorExpr:
+		pkg, ok := t.X.(*dst.Ident)
+		if !ok {
+			return ""
+		}
+		name = pkg.Name + "." + t.Sel.Name
+	default:
+		return ""
+	}
+	if docLinks {
+		return "[" + name + "]"
+	}
+	return name
+}
+
+// callbackPhrase describes a var of function type by the words of its name, e.g. "is called when an error occurs."
+// for OnError, "is called before save." for BeforeSave or "is called to parse time." for ParseTime.
+// A single word is only a purpose when it is a verb, otherwise the words are returned as they are.
+func callbackPhrase(words string) string {
+	first, rest, ok := strings.Cut(words, " ")
+	if !ok {
+		// "is called to Handler." would say nothing, the name is left to the tautology check
+		if verb := strings.ToLower(words); verbs[verb] && !isAcronym(words) {
+			return fmt.Sprintf(catalog[msgCallback], verb)
+		}
+		return words
+	}
+	switch first {
+	case "on":
+		if article(rest) == "an" {
+			return fmt.Sprintf(catalog[msgCallbackOnV], rest)
+		}
+		return fmt.Sprintf(catalog[msgCallbackOn], rest)
+	case "before":
+		return fmt.Sprintf(catalog[msgBefore], rest)
+	case "after":
+		return fmt.Sprintf(catalog[msgAfter], rest)
+	}
+	return fmt.Sprintf(catalog[msgCallback], words)
+}
+
+// reference returns the identifier value refers to, e.g. "internalHandler" for var Handler = internalHandler
+// or "http.DefaultClient", empty if value is anything else or a predeclared identifier such as nil.
+func reference(value dst.Expr) string {
+	switch t := value.(type) {
+	case *dst.Ident:
+		if types.Universe.Lookup(t.Name) != nil {
+			return ""
+		}
+		return t.Name
+	case *dst.SelectorExpr:
+		if pkg, ok := t.X.(*dst.Ident); ok {
+			return pkg.Name + "." + t.Sel.Name
+		}
+	}
+	return ""
+}
+
+// stripPackage drops the first of words when it repeats the package name pkg, e.g. "client options"
+// becomes "options" in package client. The words are kept when nothing else would be left.
+func stripPackage(words, pkg string) string {
+	first, rest, ok := strings.Cut(words, " ")
+	if !ok || !strings.EqualFold(first, pkg) {
+		return words
+	}
+	return rest
+}
+
+// errorAction phrases a method returning only an error as an action on its receiver,
+// e.g. "closes the file and returns any error." for (f *File) Close() error.
+// The rest of a multi word name is used as the object instead of the receiver, e.g. FlushBuffer.
+func errorAction(d declaration) string {
+	if d.kind != kindMethod || len(d.results) != 1 || d.results[0] != "error" {
+		return ""
+	}
+	words := strings.Fields(nameWords(d.name))
+	if len(words) == 0 {
+		return ""
+	}
+	object := strings.Join(words[1:], " ")
+	if object == "" {
+		object = nameWords(d.recvType)
+	}
+	if object == "" {
+		return ""
+	}
+	return fmt.Sprintf(catalog[msgErrorAction], thirdPerson(strings.ToLower(words[0])), object)
+}
+
+// signatureClauses returns the clauses describing the signature of fn.
+func signatureClauses(fn *dst.FuncType) []string {
+	var clauses []string
+	if clause := variadicClause(fn); clause != "" {
+		clauses = append(clauses, clause)
+	}
+	return clauses
+}
+
+// variadicClause describes a trailing variadic parameter of fn,
+// e.g. "additional behavior can be configured with opts" for functional options.
+func variadicClause(fn *dst.FuncType) string {
+	if fn.Params == nil || len(fn.Params.List) == 0 {
+		return ""
+	}
+	last := fn.Params.List[len(fn.Params.List)-1]
+	ellipsis, ok := last.Type.(*dst.Ellipsis)
+	if !ok {
+		return ""
+	}
+	if isOptionType(ellipsis.Elt) {
+		name := exprString(ellipsis.Elt)
+		if len(last.Names) > 0 && last.Names[0].Name != "_" {
+			name = last.Names[0].Name
+		}
+		return fmt.Sprintf(optionsClause, name)
+	}
+	return fmt.Sprintf(variadicFormat, exprString(ellipsis.Elt))
+}
+
+// contextSentence mentions a leading context.Context parameter of d when enabled.
+// Methods of a receiver which is itself a context are skipped, as are parameters
+// only named ctx, the check is made on the type.
+func contextSentence(d declaration) string {
+	if !mentionContext || d.fn.Params == nil || len(d.fn.Params.List) == 0 {
+		return ""
+	}
+	if d.kind == kindMethod && strings.HasSuffix(d.recvType, "Context") {
+		return ""
+	}
+	first := d.fn.Params.List[0]
+	sel, ok := first.Type.(*dst.SelectorExpr)
+	if !ok || sel.Sel.Name != "Context" {
+		return ""
+	}
+	if pkg, ok := sel.X.(*dst.Ident); !ok || pkg.Name != "context" {
+		return ""
+	}
+	name := "context"
+	if len(first.Names) > 0 && first.Names[0].Name != "_" {
+		name = first.Names[0].Name
+	}
+	return fmt.Sprintf(contextFormat, name)
+}
+
+// resultNames returns the names of the named results of fn, none if they are unnamed.
+func resultNames(fn *dst.FuncType) []string {
+	if fn == nil || fn.Results == nil {
+		return nil
+	}
+	var names []string
+	for _, field := range fn.Results.List {
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+	}
+	return names
+}
+
+// joinWords joins words as a list, e.g. "a, b and c".
+func joinWords(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + catalog[msgAnd] + words[len(words)-1]
+}
+
+// withSentence appends sentence to desc, terminating desc with a period first.
+func withSentence(desc, sentence string) string {
+	if sentence == "" {
+		return desc
+	}
+	return terminate(desc) + " " + sentence
+}
+
+// terminate ends the generated sentence s with the period of the catalog unless it already ends
+// with final punctuation, so descriptions of every kind pass ends-with-period.
+func terminate(s string) string {
+	if r, _ := utf8.DecodeLastRuneInString(s); s == "" || strings.ContainsRune(sentenceEnds, r) {
+		return s
+	}
+	return s + catalog[msgPeriod]
+}
+
+// receiverTypeName returns the name of the receiver type without pointer or type parameters,
+// e.g. "Stack" for (s *Stack[T]).
+func receiverTypeName(recv *dst.FieldList) string {
+	if recv == nil || len(recv.List) == 0 {
+		return ""
+	}
+	expr := recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *dst.StarExpr:
+			expr = t.X
+		case *dst.IndexExpr:
+			expr = t.X
+		case *dst.IndexListExpr:
+			expr = t.X
+		case *dst.ParenExpr:
+			expr = t.X
+		case *dst.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// fieldTypes returns the type of each field of fields, a field declaring several names counts once per name.
+func fieldTypes(fields *dst.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var list []string
+	for _, field := range fields.List {
+		for i := 0; i < len(field.Names) || i == 0; i++ {
+			list = append(list, exprString(field.Type))
+		}
+	}
+	return list
+}
+
+// isOptionType reports whether expr names a functional option type, e.g. Option or grpc.DialOption.
+func isOptionType(expr dst.Expr) bool {
+	switch t := expr.(type) {
+	case *dst.Ident:
+		return strings.HasSuffix(t.Name, "Option")
+	case *dst.SelectorExpr:
+		return strings.HasSuffix(t.Sel.Name, "Option")
+	case *dst.StarExpr:
+		return isOptionType(t.X)
+	}
+	return false
+}
+
+// exprString renders a type expression the way it is written in source.
+func exprString(expr dst.Expr) string {
+	switch t := expr.(type) {
+	case *dst.Ident:
+		return t.Name
+	case *dst.SelectorExpr:
+		return exprString(t.X) + "." + t.Sel.Name
+	case *dst.StarExpr:
+		return "*" + exprString(t.X)
+	case *dst.Ellipsis:
+		return "..." + exprString(t.Elt)
+	case *dst.ArrayType:
+		if t.Len == nil {
+			return "[]" + exprString(t.Elt)
+		}
+		return "[" + exprString(t.Len) + "]" + exprString(t.Elt)
+	case *dst.MapType:
+		return "map[" + exprString(t.Key) + "]" + exprString(t.Value)
+	case *dst.ChanType:
+		switch t.Dir {
+		case dst.SEND:
+			return "chan<- " + exprString(t.Value)
+		case dst.RECV:
+			return "<-chan " + exprString(t.Value)
+		}
+		return "chan " + exprString(t.Value)
+	case *dst.FuncType:
+		return "func(...)"
+	case *dst.InterfaceType:
+		if t.Methods == nil || len(t.Methods.List) == 0 {
+			return "interface{}"
+		}
+		return "interface{...}"
+	case *dst.StructType:
+		if t.Fields == nil || len(t.Fields.List) == 0 {
+			return "struct{}"
+		}
+		return "struct{...}"
+	case *dst.BasicLit:
+		return t.Value
+	case *dst.ParenExpr:
+		return "(" + exprString(t.X) + ")"
+	case *dst.IndexExpr:
+		return exprString(t.X) + "[" + exprString(t.Index) + "]"
+	case *dst.IndexListExpr:
+		indices := make([]string, len(t.Indices))
+		for i, index := range t.Indices {
+			indices[i] = exprString(index)
+		}
+		return exprString(t.X) + "[" + strings.Join(indices, ", ") + "]"
+	}
+	return "?"
+}
+
+// wrapComment splits the line comment doc into lines no longer than width, 0 disables wrapping.
+// A single word longer than width is kept on its own line.
+func wrapComment(doc string, width int) []string {
+	if width <= 0 || len(doc) <= width || !strings.HasPrefix(doc, "// ") {
+		return []string{doc}
+	}
+	var lines []string
+	line := "//"
+	for _, word := range strings.Fields(strings.TrimPrefix(doc, "// ")) {
+		if line != "//" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = "//"
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
+
+// article returns the indefinite article for phrase.
+// The first letter decides, except for words whose first sound is not the sound of their first letter,
+// e.g. "a user" and "an hour".
+func article(phrase string) string {
+	phrase = strings.TrimPrefix(phrase, "[")
+	// acronyms are spelled out, e.g. "an HTTP server" but "a DTO"
+	if first, _, _ := strings.Cut(phrase, " "); isAcronym(strings.TrimSuffix(first, "]")) {
+		if strings.ContainsRune("AEFHILMNORSX", rune(first[0])) {
+			return "an"
+		}
+		return "a"
+	}
+	phrase = strings.ToLower(phrase)
+	if phrase == "" {
+		return "a"
+	}
+	for _, prefix := range []string{"uni", "use", "usa", "usu", "uti", "uu", "eu", "one", "once"} {
+		if strings.HasPrefix(phrase, prefix) {
+			return "a"
+		}
+	}
+	for _, prefix := range []string{"hour", "honest", "honor", "heir"} {
+		if strings.HasPrefix(phrase, prefix) {
+			return "an"
+		}
+	}
+	if strings.ContainsRune("aeiou", rune(phrase[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// isPlural reports whether the last word of phrase looks like a plural noun.
+func isPlural(phrase string) bool {
+	words := strings.Fields(phrase)
+	if len(words) == 0 {
+		return false
+	}
+	last := words[len(words)-1]
+	if len(last) < 3 || !strings.HasSuffix(last, "s") {
+		return false
+	}
+	for _, suffix := range []string{"ss", "us", "is"} {
+		if strings.HasSuffix(last, suffix) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,337 @@
+package repair
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNameWords(t *testing.T) {
+	saved := symbols
+	t.Cleanup(func() { symbols = saved })
+	symbols = packageSymbols{names: map[string]bool{"DTO": true, "Close": true}}
+	tests := []struct {
+		name, want string
+	}{
+		{"UserService", "user service"},
+		{"DTO", "DTO"},
+		{"ParseJSON", "parse JSON"},
+		{"ConvertUserToDTO", "convert user to DTO"},
+		{"HTTPServer", "HTTP server"},
+		{"Server2", "Server2"},
+		{"Run2", "Run2"},
+		{"DecodeBase64", "decode base64"},
+		{"CamelCase2", "camel case2"},
+		{"NewSHA256", "new SHA256"},
+	}
+	for _, tt := range tests {
+		if got := nameWords(tt.name); got != tt.want {
+			t.Errorf("nameWords(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := conjugate(nameWords("CloseAll")); got != "closes all" {
+		t.Errorf("conjugate(nameWords(%q)) = %q, want the verb conjugated", "CloseAll", got)
+	}
+}
+
+func TestArticleAcronym(t *testing.T) {
+	for phrase, want := range map[string]string{"HTTP server": "an", "DTO": "a", "SQL row": "an", "user": "a", "[HTTP] server": "an"} {
+		if got := article(phrase); got != want {
+			t.Errorf("article(%q) = %q, want %q", phrase, got, want)
+		}
+	}
+}
+
+// A name kept as written describes nothing and leaves the comment format, even with --articles.
+func TestDescribeAcronymsAndDigits(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": `package store
+
+type DTO struct{}
+
+type Server2 struct{}
+
+func Run2() {}
+
+func ParseJSON() {}
+
+type HTTPServer struct{}
+`})
+	if _, stderr, code := run(t, dir, "-auto-description", "-articles"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["a.go"]
+	for _, want := range []string{
+		"// DTO missing godoc.\n",
+		"// Server2 missing godoc.\n",
+		"// Run2 missing godoc.\n",
+		"// ParseJSON parses JSON.\n",
+		"// HTTPServer is an HTTP server.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestDescribeConstructor(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": `package server
+
+type Server struct{}
+
+type Option func(*Server)
+
+func NewServer(opts ...Option) *Server { return nil }
+
+func NewOption() Option { return nil }
+
+func NewName() string { return "" }
+`})
+	if _, stderr, code := run(t, dir, "-auto-description"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["a.go"]
+	for _, want := range []string{
+		"// NewServer returns a new Server; additional behavior can be configured with opts.\n",
+		"// NewOption returns a new Option.\n",
+		// not a constructor, the result is not the type named
+		"// NewName new name.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestDescribeErrorAction(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": `package p
+
+type File struct{}
+
+func (f *File) Close() error { return nil }
+
+func (f *File) FlushBuffer() error { return nil }
+
+func (f *File) Sync() (int, error) { return 0, nil }
+
+func Close() error { return nil }
+`})
+	if _, stderr, code := run(t, dir, "-auto-description"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["a.go"]
+	for _, want := range []string{
+		"// Close closes the File and returns any error.\nfunc (f *File) Close() error",
+		// the rest of the name is the object instead of the receiver
+		"// FlushBuffer flushes the buffer and returns any error.\n",
+		// only methods returning nothing but an error are actions
+		"// Sync syncs.\n",
+		"// Close closes.\nfunc Close() error",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestMentionResults(t *testing.T) {
+	src := "package p\n\nfunc Parse() (n int, err error) { return }\n\nfunc Load() (int, error) { return 0, nil }\n\nfunc Pair() (a, b int) { return }\n"
+	dir := writeTree(t, map[string]string{"a.go": src})
+	if _, stderr, code := run(t, dir, "-auto-description", "-mention-results"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["a.go"]
+	for _, want := range []string{
+		"// Parse parses and returns n and err.\n",
+		// unnamed results are not mentioned
+		"// Load loads.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+
+	dir = writeTree(t, map[string]string{"a.go": src})
+	if _, stderr, code := run(t, dir, "-func-format", "// {{.Name}} returns{{range .ResultNames}} {{.}}{{else}} nothing named{{end}}."); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got = readTree(t, dir)["a.go"]
+	for _, want := range []string{"// Parse returns n err.\n", "// Load returns nothing named.\n", "// Pair returns a b.\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestDescribeSingleWord(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": "package p\n\ntype Server struct{}\n\nvar Config int\n\nfunc Run() {}\n\ntype UserStore struct{}\n"})
+	if _, stderr, code := run(t, dir, "-auto-description"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["a.go"]
+	for _, want := range []string{
+		// "Server server." only repeats the name
+		"// Server missing godoc.\n",
+		"// Config missing godoc.\n",
+		// a conjugated verb says more than the name
+		"// Run runs.\n",
+		"// UserStore user store.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestDescribeValueAlias(t *testing.T) {
+	src := "package p\n\nimport \"strings\"\n\nfunc internalHandler() {}\n\nvar Handler = internalHandler\n\nvar Split = strings.Split\n\nvar Count = 3\n"
+	dir := writeTree(t, map[string]string{"a.go": src})
+	if _, stderr, code := run(t, dir, "-auto-description"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["a.go"]
+	for _, want := range []string{
+		"// Handler is an alias for internalHandler.\n",
+		"// Split is an alias for strings.Split.\n",
+		// a literal is not a reference to another identifier
+		"// Count missing godoc.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+	// without --auto-description values are documented by name only
+	dir = writeTree(t, map[string]string{"a.go": src})
+	run(t, dir)
+	if got := readTree(t, dir)["a.go"]; !strings.Contains(got, "// Handler missing godoc.\n") {
+		t.Errorf("a.go lacks the default doc of Handler:\n%s", got)
+	}
+}
+
+func TestDescribeFieldTags(t *testing.T) {
+	src := "package p\n\ntype Config struct {\n\tPort int `yaml:\"port\"`\n\tHost string `json:\"host,omitempty\" env:\"HOST\"`\n" +
+		"\tDebug bool `json:\"-\"`\n\tID string\n\tMaxConns int `yaml:\"max_conns\"`\n}\n"
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-auto-description", "-fields", "-use-tags"}, []string{
+			"// Port is the port (yaml: port).\n",
+			"// Host is the host (json: host, env: HOST).\n",
+			// names such as - are left out
+			"// Debug is the debug.\n",
+			"// ID is the ID.\n",
+			"// MaxConns is the max conns (yaml: max_conns).\n",
+		}},
+		{[]string{"-auto-description", "-fields"}, []string{
+			"// Port is the port.\n",
+			"// Host is the host.\n",
+		}},
+	}
+	for _, tt := range tests {
+		dir := writeTree(t, map[string]string{"a.go": src})
+		if _, stderr, code := run(t, dir, tt.args...); code != 0 {
+			t.Fatalf("%v: exit status %d: %s", tt.args, code, stderr)
+		}
+		got := readTree(t, dir)["a.go"]
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%v: a.go lacks %q:\n%s", tt.args, want, got)
+			}
+		}
+	}
+}
+
+func TestDescribeCallback(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": `package p
+
+var OnError func(error)
+
+var OnClose func()
+
+var BeforeSave func(string) bool
+
+var AfterLoad func()
+
+var ParseTime func(string) (int, error)
+
+var Validate = func() error { return nil }
+
+var Handler = func() {}
+
+var Count int
+`})
+	if _, stderr, code := run(t, dir, "-auto-description"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["a.go"]
+	for _, want := range []string{
+		"// OnError is called when an error occurs.\n",
+		"// OnClose is called when a close occurs.\n",
+		"// BeforeSave is called before save.\n",
+		"// AfterLoad is called after load.\n",
+		"// ParseTime is called to parse time.\n",
+		"// Validate is called to validate.\n",
+		// a single word which is not a verb names no purpose
+		"// Handler missing godoc.\n",
+		"// Count missing godoc.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestDescribeConstraint(t *testing.T) {
+	src := `package p
+
+type Ordered interface {
+	~int | ~float64
+}
+
+type Bytes interface{ []byte }
+
+type Comparable interface {
+	comparable
+}
+
+type Number interface {
+	int | int64
+	String() string
+}
+
+type Any interface{ any }
+
+type Err interface{ error }
+`
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-auto-description"}, []string{
+			"// Ordered is a constraint permitting ~int | ~float64.\n",
+			"// Bytes is a constraint permitting []byte.\n",
+			"// Comparable is a constraint permitting comparable.\n",
+			// interfaces with methods are not described by their type set
+			"// Number missing godoc.\n",
+			// embedding a predeclared interface keeps an interface ordinary
+			"// Any missing godoc.\n",
+			"// Err missing godoc.\n",
+		}},
+		{[]string{"-format", "// {{.Name}}{{if .IsConstraint}} permits {{.TypeSet}}{{else}} is an interface{{end}}."}, []string{
+			"// Ordered permits ~int | ~float64.\n",
+			"// Number is an interface.\n",
+			"// Any is an interface.\n",
+		}},
+	}
+	for _, tt := range tests {
+		dir := writeTree(t, map[string]string{"a.go": src})
+		if _, stderr, code := run(t, dir, tt.args...); code != 0 {
+			t.Fatalf("%v: exit status %d: %s", tt.args, code, stderr)
+		}
+		got := readTree(t, dir)["a.go"]
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%v: a.go lacks %q:\n%s", tt.args, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package repair
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+)
+
+// describeRequest is the JSON payload passed to --describe-cmd on stdin.
+type describeRequest struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Package   string `json:"package"`
+	Receiver  string `json:"receiver,omitempty"`
+	Signature string `json:"signature"`
+}
+
+// commandDescription returns the description of d printed by --describe-cmd, false if there is no command,
+// or if it fails, times out or prints nothing, in which case the built-in description is used.
+func commandDescription(ctx context.Context, d declaration) (string, bool) {
+	args := strings.Fields(describeCmd)
+	if len(args) == 0 {
+		return "", false
+	}
+	payload, err := json.Marshal(describeRequest{
+		Name:      d.name,
+		Kind:      d.kind,
+		Package:   d.pkg,
+		Receiver:  d.receiver,
+		Signature: declSignature(d),
+	})
+	if err != nil {
+		return "", false
+	}
+	cmdCtx, cancel := context.WithTimeout(ctx, describeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(cmdCtx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	// the run itself was canceled, it is aborted anyway
+	if ctx.Err() != nil {
+		return "", false
+	}
+	if cmdCtx.Err() != nil {
+		log.Printf("describe command timed out after %s for %s, using the default description", describeTimeout, d.symbol())
+		return "", false
+	}
+	if err != nil {
+		log.Printf("describe command failed for %s, using the default description: %v: %s", d.symbol(), err, strings.TrimSpace(stderr.String()))
+		return "", false
+	}
+	desc := strings.Join(strings.Fields(string(out)), " ")
+	return desc, desc != ""
+}
+
+// declSignature returns the declaration of d as written in Go without its body, e.g. "func (s *Store) Get(key string) (string, error)".
+func declSignature(d declaration) string {
+	switch {
+	case d.fn != nil:
+		var recv string
+		if d.receiver != "" {
+			recv = "(" + d.receiver + ") "
+		}
+		return fmt.Sprintf("func %s%s(%s)%s", recv, d.name, joinParams(templateParams(d.fn.Params)), resultList(templateParams(d.fn.Results)))
+	case d.spec != nil:
+		return fmt.Sprintf("type %s %s", d.name, exprString(d.spec.Type))
+	case d.value != nil:
+		return fmt.Sprintf("%s %s = %s", d.kind, d.name, exprString(d.value))
+	}
+	return d.kind + " " + d.name
+}
+
+// joinParams joins params as written in a parameter list.
+func joinParams(params []templateParam) string {
+	list := make([]string, 0, len(params))
+	for _, p := range params {
+		if p.Name == p.Type {
+			list = append(list, p.Type)
+		} else {
+			list = append(list, p.Name+" "+p.Type)
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// resultList returns results as written after the parameters of a function.
+func resultList(results []templateParam) string {
+	switch {
+	case len(results) == 0:
+		return ""
+	case len(results) == 1 && results[0].Name == results[0].Type:
+		return " " + results[0].Type
+	}
+	return " (" + joinParams(results) + ")"
+}
@@ -0,0 +1,73 @@
+package repair
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// describeScript writes a shell script running src and returns the --describe-cmd running it.
+func describeScript(t *testing.T, src string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the describe command is a shell script")
+	}
+	script := filepath.Join(t.TempDir(), "describe.sh")
+	if err := os.WriteFile(script, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return "sh " + script
+}
+
+func TestDescribeCmd(t *testing.T) {
+	payload := filepath.Join(t.TempDir(), "payload.json")
+	cmd := describeScript(t, "cat >"+payload+"\necho '  fetches the value\n  of key.'\n")
+	dir := writeTree(t, map[string]string{"a.go": "package store\n\n// Store is a store.\ntype Store struct{}\n\nfunc (s *Store) Get(key string) (string, error) { return key, nil }\n"})
+	if _, stderr, code := run(t, dir, "-auto-description", "-describe-cmd", cmd); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if got := readTree(t, dir)["a.go"]; !strings.Contains(got, "// Get fetches the value of key.\nfunc (s *Store) Get(") {
+		t.Errorf("a.go lacks the description of the command:\n%s", got)
+	}
+	data, err := os.ReadFile(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req describeRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatal(err)
+	}
+	want := describeRequest{Name: "Get", Kind: kindMethod, Package: "store", Receiver: "*Store", Signature: "func (*Store) Get(key string) (string, error)"}
+	if req != want {
+		t.Errorf("payload %+v, want %+v", req, want)
+	}
+}
+
+func TestDescribeCmdFallback(t *testing.T) {
+	tests := []struct {
+		name, src string
+		args      []string
+		log       string
+	}{
+		{"failing", "cat >/dev/null\necho broken >&2\nexit 3\n", nil, "describe command failed for Run, using the default description: exit status 3: broken"},
+		{"silent", "cat >/dev/null\n", nil, ""},
+		{"slow", "cat >/dev/null\nsleep 1\necho late.\n", []string{"-describe-timeout", "100ms"}, "describe command timed out after 100ms for Run"},
+	}
+	for _, tt := range tests {
+		dir := writeTree(t, map[string]string{"a.go": "package p\n\nfunc Run() {}\n"})
+		args := append([]string{"-auto-description", "-describe-cmd", describeScript(t, tt.src)}, tt.args...)
+		_, stderr, code := run(t, dir, args...)
+		if code != 0 {
+			t.Fatalf("%s: exit status %d: %s", tt.name, code, stderr)
+		}
+		if !strings.Contains(stderr, tt.log) {
+			t.Errorf("%s: logs lack %q:\n%s", tt.name, tt.log, stderr)
+		}
+		if got := readTree(t, dir)["a.go"]; !strings.Contains(got, "// Run runs.\n") {
+			t.Errorf("%s: a.go lacks the built-in description:\n%s", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package repair
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const exampleFile = "example_test.go"
+
+// exampleDir adds Example function stubs to example_test.go of dir for every exported
+// function, type and method of the package which has no example yet.
+func exampleDir(ctx context.Context, fsys fs.FS, dir string) error {
+	fset := token.NewFileSet()
+	filter := func(entry fs.DirEntry) bool {
+		return sizeFilter(path.Join(dir, entry.Name()), entry) && generatedFilter(fsys, dir, entry)
+	}
+	pkgs, err := parseDir(fset, fsys, dir, filter)
+	if err != nil {
+		return fmt.Errorf("failed parsing go files in directory %s: %v", dir, err)
+	}
+
+	existing := make(map[string]bool)
+	var pkg *ast.Package
+	names := make([]string, 0, len(pkgs))
+	for name := range pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := pkgs[name]
+		for _, fileName := range sortedFiles(p) {
+			if !strings.HasSuffix(fileName, "_test.go") {
+				continue
+			}
+			for _, decl := range p.Files[fileName].Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && isTestName(fn.Name.Name, "Example") {
+					existing[fn.Name.Name] = true
+				}
+			}
+		}
+		if !strings.HasSuffix(name, "_test") && name != "main" {
+			pkg = p
+		}
+	}
+	if pkg == nil {
+		return nil
+	}
+
+	var stubs []string
+	for _, target := range exampleTargets(pkg) {
+		if hasExample(existing, target.name) {
+			continue
+		}
+		stubs = append(stubs, fmt.Sprintf("func Example%s() {\n%s\t// Output:\n}\n", target.name, target.call))
+	}
+	if len(stubs) == 0 {
+		return nil
+	}
+
+	wfs, ok := fsys.(writeFS)
+	if !ok {
+		return fmt.Errorf("failed writing examples of package %s: file system is read-only", pkg.Name)
+	}
+	fileName := path.Join(dir, exampleFile)
+	src, err := fs.ReadFile(fsys, fileName)
+	source := contentHash(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		source = ""
+		src = []byte(fmt.Sprintf("package %s\n", pkg.Name))
+	} else if err != nil {
+		return fmt.Errorf("failed reading file %s: %v", fileName, err)
+	}
+	src = append(src, "\n"+strings.Join(stubs, "\n")...)
+	out, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("failed formatting file %s: %v", fileName, err)
+	}
+	stage(wfs, fileName, pkg.Name, source, out, 0644, nil)
+	return nil
+}
+
+// exampleTarget is an exported identifier an example can be written for.
+type exampleTarget struct {
+	// name is the example name without the Example prefix, e.g. "Type_Method".
+	name string
+	// call is the commented out usage placed in the stub.
+	call string
+}
+
+// exampleTargets returns the exported functions, types and methods of exported types of pkg
+// in file name and source order, named after the rules godoc uses to associate examples.
+func exampleTargets(pkg *ast.Package) []exampleTarget {
+	var targets []exampleTarget
+	for _, fileName := range sortedFiles(pkg) {
+		if strings.HasSuffix(fileName, "_test.go") {
+			continue
+		}
+		for _, decl := range pkg.Files[fileName].Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if !d.Name.IsExported() {
+					continue
+				}
+				if d.Recv == nil {
+					targets = append(targets, exampleTarget{
+						name: d.Name.Name,
+						call: fmt.Sprintf("\t// %s()\n", d.Name.Name),
+					})
+					continue
+				}
+				recv := recvTypeName(d.Recv)
+				if !token.IsExported(recv) {
+					continue
+				}
+				targets = append(targets, exampleTarget{
+					name: recv + "_" + d.Name.Name,
+					call: fmt.Sprintf("\t// var v %s\n\t// v.%s()\n", recv, d.Name.Name),
+				})
+			case *ast.GenDecl:
+				if d.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range d.Specs {
+					if s := spec.(*ast.TypeSpec); s.Name.IsExported() {
+						targets = append(targets, exampleTarget{
+							name: s.Name.Name,
+							call: fmt.Sprintf("\t// var v %s\n", s.Name.Name),
+						})
+					}
+				}
+			}
+		}
+	}
+	return targets
+}
+
+// hasExample reports whether existing contains an example for name, either Example<name>
+// or Example<name>_suffix where suffix starts with a lower case letter.
+func hasExample(existing map[string]bool, name string) bool {
+	base := "Example" + name
+	for example := range existing {
+		if example == base {
+			return true
+		}
+		if !strings.HasPrefix(example, base+"_") {
+			continue
+		}
+		r, _ := utf8.DecodeRuneInString(example[len(base)+1:])
+		if unicode.IsLower(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// recvTypeName returns the name of the receiver type without pointer or type parameters.
+func recvTypeName(recv *ast.FieldList) string {
+	if recv == nil || len(recv.List) == 0 {
+		return ""
+	}
+	expr := recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
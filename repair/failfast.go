@@ -0,0 +1,28 @@
+package repair
+
+import "errors"
+
+// errFailFast stops the walk at the first failing finding with --fail-fast.
+var errFailFast = errors.New("stopped at the first finding")
+
+// grandfathered holds the entries of the --baseline with --fail-fast, whose findings do not stop the walk.
+var grandfathered map[baselineEntry]bool
+
+// firstFailure returns the index of the first finding of list making the run fail and not in the baseline,
+// -1 if there is none.
+func firstFailure(list []finding) int {
+	for i, f := range list {
+		if (warningsAsErrors || ruleSeverity(f.rule) == severityError) && !grandfathered[baselineKey(f)] {
+			return i
+		}
+	}
+	return -1
+}
+
+// stopAtFirstFailure keeps the findings up to the first one making the run fail, in order of position.
+func stopAtFirstFailure() {
+	sortFindings()
+	if i := firstFailure(findings); i >= 0 {
+		findings = findings[:i+1]
+	}
+}
@@ -0,0 +1,93 @@
+package repair
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"strconv"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// instrumentFields documents the exported fields of s with --fields, if it is an exported struct type.
+// A field declaring several names is documented after the first one, as values are.
+func instrumentFields(ctx context.Context, s *dst.TypeSpec, newDecl func(name, kind string, n dst.Node) declaration) {
+	st, ok := s.Type.(*dst.StructType)
+	if !documentFields || !ok || !s.Name.IsExported() {
+		return
+	}
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			continue
+		}
+		d := newDecl(field.Names[0].Name, kindField, field)
+		d.recvType = s.Name.Name
+		if field.Tag != nil {
+			d.tag = field.Tag.Value
+		}
+		before := strings.Join(field.Decs.Start.All(), "\n")
+		field.Decs.Start = autoDecl(ctx, d, field.Decs.Start)
+		// the doc needs a line of its own, in a struct written on a single line as well
+		if strings.Join(field.Decs.Start.All(), "\n") != before {
+			field.Decs.Before = dst.NewLine
+			st.Fields.List[len(st.Fields.List)-1].Decs.After = dst.NewLine
+		}
+	}
+}
+
+// eachField calls fn with the exported fields of s and their doc with --fields, if it is an exported struct type.
+func eachField(s *ast.TypeSpec, newDecl func(name, kind string, n ast.Node) declaration, fn func(declaration, *ast.CommentGroup)) {
+	st, ok := s.Type.(*ast.StructType)
+	if !documentFields || !ok || !s.Name.IsExported() {
+		return
+	}
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			continue
+		}
+		d := newDecl(field.Names[0].Name, kindField, field)
+		d.recvType = s.Name.Name
+		if field.Tag != nil {
+			d.tag = field.Tag.Value
+		}
+		fn(d, field.Doc)
+	}
+}
+
+// tagHint returns the names given to a field by its tag with --use-tags, e.g. " (yaml: port)"
+// for `yaml:"port,omitempty"`, empty if there are none. Names such as "-" which leave out the field are not mentioned.
+func tagHint(tag string) string {
+	if !useTags || tag == "" {
+		return ""
+	}
+	tag, err := strconv.Unquote(tag)
+	if err != nil {
+		return ""
+	}
+	var names []string
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		i := strings.Index(tag, ":")
+		if i <= 0 {
+			break
+		}
+		key := tag[:i]
+		quoted, err := strconv.QuotedPrefix(tag[i+1:])
+		if err != nil {
+			break
+		}
+		tag = tag[i+1+len(quoted):]
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			break
+		}
+		if name, _, _ := strings.Cut(value, ","); name != "" && name != "-" {
+			names = append(names, fmt.Sprintf("%s: %s", key, name))
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(names, ", ") + ")"
+}
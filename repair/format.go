@@ -0,0 +1,300 @@
+package repair
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/dave/dst"
+)
+
+// templateData is the data available to comment formats written as text/template, e.g. "// {{.Name}} ...".
+type templateData struct {
+	// Name is the name of the declaration.
+	Name string
+	// Kind is one of type, func, method, const, var or package.
+	Kind string
+	// Package is the name of the package of the declaration.
+	Package string
+	// Receiver is the receiver type of a method as written in source, e.g. "*Stack[T]", empty otherwise.
+	Receiver string
+	// Params are the parameters of a function, one per name, e.g. two for (a, b string).
+	Params []templateParam
+	// Results are the results of a function, one per name.
+	Results []templateParam
+	// ResultNames are the names of the results of a function, empty when they are unnamed.
+	ResultNames []string
+	// IsConstraint is set for interfaces which can only be used as constraints, e.g. interface{ ~int | ~float64 }.
+	IsConstraint bool
+	// TypeSet is the type set of a constraint as written, e.g. "~int | ~float64".
+	TypeSet string
+}
+
+// templateParam is a parameter or result of a function. Unnamed ones are named by their type,
+// a variadic parameter has a type such as "...string".
+type templateParam struct {
+	Name string
+	Type string
+}
+
+// todoOwnerPattern restricts owners so they cannot end the TODO marker or the comment early.
+var todoOwnerPattern = regexp.MustCompile(`^[A-Za-z0-9_.@/-]+$`)
+
+// todoFormat returns the comment format attributing placeholders to owner, picked up by TODO trackers.
+func todoFormat(owner string) (string, error) {
+	if !todoOwnerPattern.MatchString(owner) {
+		return "", fmt.Errorf("invalid TODO owner %q, only letters, digits and _.@/- are allowed", owner)
+	}
+	return fmt.Sprintf(catalog[msgTodo], owner), nil
+}
+
+// isPlaceholder reports whether comment is a placeholder written by the tool for d rather than
+// documentation written by hand: the default format, the configured format or a TODO marker.
+func isPlaceholder(comment string, d declaration) bool {
+	if comment == fmt.Sprintf(defaultCommentFormat, d.name) || comment == fmt.Sprintf(catalog[msgPlaceholder], d.name) ||
+		comment == formatComment(d) {
+		return true
+	}
+	// render the TODO marker with a separator as owner to learn what surrounds the owner
+	todo := strings.SplitN(fmt.Sprintf(fmt.Sprintf(catalog[msgTodo], "\x00"), d.name), "\x00", 2)
+	prefix, suffix := todo[0], todo[1]
+	if len(comment) < len(prefix)+len(suffix) || !strings.HasPrefix(comment, prefix) || !strings.HasSuffix(comment, suffix) {
+		return false
+	}
+	return todoOwnerPattern.MatchString(comment[len(prefix) : len(comment)-len(suffix)])
+}
+
+// formatRule applies format to the files matching glob, see --format-map.
+type formatRule struct {
+	glob   string
+	format string
+	tmpl   *template.Template
+}
+
+// formatRules holds the rules of --format-map in the order given, the first matching one applies.
+type formatRules []formatRule
+
+func (r *formatRules) String() string {
+	var rules []string
+	for _, rule := range *r {
+		rules = append(rules, rule.glob+"="+rule.format)
+	}
+	return strings.Join(rules, ",")
+}
+
+// Set adds the rule glob=format, the format is validated as --format is.
+func (r *formatRules) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("format map %q is not of the form glob=format", value)
+	}
+	glob, format := value[:i], value[i+1:]
+	if _, err := path.Match(glob, ""); err != nil {
+		return fmt.Errorf("invalid glob %q: %v", glob, err)
+	}
+	tmpl, err := parseFormat(format)
+	if err != nil {
+		return fmt.Errorf("invalid comment format for %s: %v", glob, err)
+	}
+	*r = append(*r, formatRule{glob: glob, format: format, tmpl: tmpl})
+	return nil
+}
+
+// match returns the first rule whose glob matches fileName, the path relative to the code path.
+// Globs without a slash are also matched against the base name, e.g. "*_api.go".
+func (r formatRules) match(fileName string) (formatRule, bool) {
+	for _, rule := range r {
+		if ok, _ := path.Match(rule.glob, fileName); ok {
+			return rule, true
+		}
+		if !strings.Contains(rule.glob, "/") {
+			if ok, _ := path.Match(rule.glob, path.Base(fileName)); ok {
+				return rule, true
+			}
+		}
+	}
+	return formatRule{}, false
+}
+
+// useFormat makes the format of the first rule matching fileName the comment format, or that of
+// --internal-format for files of internal packages, and returns a function restoring the previous one.
+func useFormat(fileName string) func() {
+	rule, ok := formatMap.match(fileName)
+	if !ok && internalRule.format != "" && inInternal(fileName) {
+		rule, ok = internalRule, true
+	}
+	if !ok {
+		return func() {}
+	}
+	format, tmpl := commentFormat, commentTemplate
+	commentFormat, commentTemplate = rule.format, rule.tmpl
+	return func() {
+		commentFormat, commentTemplate = format, tmpl
+	}
+}
+
+// internalRule applies --internal-format to the files of internal packages, it has no glob.
+var internalRule formatRule
+
+// inInternal reports whether fileName, the path relative to the code path, lies in an internal directory.
+func inInternal(fileName string) bool {
+	for _, dir := range strings.Split(path.Dir(fileName), "/") {
+		if dir == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// kindFormats holds the formats of --func-format and --method-format by kind, they take precedence
+// over the format of the file so a function and a method of the same name get their own.
+var kindFormats = map[string]formatRule{}
+
+// commentTemplate is the parsed comment format when it is a template, nil for printf formats.
+var commentTemplate *template.Template
+
+// isTemplate reports whether format is a text/template rather than a printf format.
+func isTemplate(format string) bool {
+	return strings.Contains(format, "{{")
+}
+
+// parseFormat validates format and returns the parsed template, or nil for printf formats.
+// Templates are executed once against sample data so references to unknown fields are reported,
+// printf formats must contain exactly one %s verb. Either way the rendered sample has to be a comment.
+func parseFormat(format string) (*template.Template, error) {
+	if !isTemplate(format) {
+		if err := validatePrintf(format, 1); err != nil {
+			return nil, err
+		}
+		return nil, validateComment(format, fmt.Sprintf(format, "Name"))
+	}
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		return nil, err
+	}
+	sample := templateData{
+		Name:         "Name",
+		Kind:         kindMethod,
+		Package:      "pkg",
+		Receiver:     "*Type",
+		Params:       []templateParam{{Name: "ctx", Type: "context.Context"}},
+		Results:      []templateParam{{Name: "err", Type: "error"}},
+		ResultNames:  []string{"err"},
+		IsConstraint: true,
+		TypeSet:      "~int | ~float64",
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, validateComment(format, buf.String())
+}
+
+// validateComment checks that every line of doc, rendered from format, is part of a // or /* */ comment,
+// so the format cannot insert code or text breaking the file. Empty lines are dropped when inserting.
+func validateComment(format, doc string) error {
+	block := false
+	for _, line := range strings.Split(doc, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !block {
+			if strings.HasPrefix(line, "//") {
+				continue
+			}
+			if !strings.HasPrefix(line, "/*") {
+				return fmt.Errorf("format %q renders %q, which is not a // or /* */ comment", format, line)
+			}
+			line, block = line[2:], true
+		}
+		if i := strings.Index(line, "*/"); i >= 0 {
+			if rest := strings.TrimSpace(line[i+2:]); rest != "" {
+				return fmt.Errorf("format %q renders %q after the end of the comment", format, rest)
+			}
+			block = false
+		}
+	}
+	if block {
+		return fmt.Errorf("format %q renders an unterminated /* comment", format)
+	}
+	return nil
+}
+
+// validatePrintf checks that format contains exactly want %s verbs and no other verbs.
+func validatePrintf(format string, want int) error {
+	count := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		if i == len(format) {
+			return fmt.Errorf("format %q ends with a lone %%", format)
+		}
+		switch format[i] {
+		case '%':
+		case 's':
+			count++
+		default:
+			return fmt.Errorf("format %q contains unsupported verb %%%c, only %%s is allowed", format, format[i])
+		}
+	}
+	if count != want {
+		return fmt.Errorf("format %q contains %d %%s verbs, expected %d", format, count, want)
+	}
+	return nil
+}
+
+// formatComment renders the comment format for d, that of its kind if given.
+func formatComment(d declaration) string {
+	format, tmpl := commentFormat, commentTemplate
+	if rule, ok := kindFormats[d.kind]; ok {
+		format, tmpl = rule.format, rule.tmpl
+	}
+	if tmpl == nil {
+		return fmt.Sprintf(format, d.name)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, d.templateData()); err != nil {
+		log.Printf("failed executing comment format for %s, using the default: %v", d.name, err)
+		return fmt.Sprintf(defaultCommentFormat, d.name)
+	}
+	return buf.String()
+}
+
+// templateData returns the data templates are executed with for d.
+func (d declaration) templateData() templateData {
+	data := templateData{Name: d.name, Kind: d.kind, Package: d.pkg, Receiver: d.receiver}
+	if d.fn != nil {
+		data.Params = templateParams(d.fn.Params)
+		data.Results = templateParams(d.fn.Results)
+		data.ResultNames = resultNames(d.fn)
+	}
+	if d.spec != nil {
+		data.TypeSet, data.IsConstraint = constraintTypes(d.spec)
+	}
+	return data
+}
+
+// templateParams returns the parameters of fields, a field declaring several names gives one per name.
+func templateParams(fields *dst.FieldList) []templateParam {
+	if fields == nil {
+		return nil
+	}
+	var params []templateParam
+	for _, field := range fields.List {
+		typ := exprString(field.Type)
+		if len(field.Names) == 0 {
+			params = append(params, templateParam{Name: typ, Type: typ})
+		}
+		for _, name := range field.Names {
+			params = append(params, templateParam{Name: name.Name, Type: typ})
+		}
+	}
+	return params
+}
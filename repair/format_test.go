@@ -0,0 +1,225 @@
+package repair
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sameNameSrc = `package config
+
+type Config struct{}
+
+func Validate(c *Config) error {
+	return c.Validate()
+}
+
+func (c *Config) Validate() error {
+	return nil
+}
+
+func (Config) Name() string {
+	return "config"
+}
+`
+
+func TestKindFormats(t *testing.T) {
+	dir := writeTree(t, map[string]string{"config.go": sameNameSrc})
+	_, stderr, code := run(t, dir, "-quiet-success",
+		"-func-format", "// %s is a function.",
+		"-method-format", "// {{.Name}} is a method of {{.Receiver}}.")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["config.go"]
+	for _, want := range []string{
+		"// Config missing godoc.\ntype Config struct{}",
+		"// Validate is a function.\nfunc Validate(c *Config) error",
+		"// Validate is a method of *Config.\nfunc (c *Config) Validate() error",
+		"// Name is a method of Config.\nfunc (Config) Name() string",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("repaired file lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestKindFormatsCheck(t *testing.T) {
+	// docs of the format of their kind are placeholders, the function and the method are each recognized
+	src := strings.NewReplacer(
+		"func Validate(", "// Validate is a function.\nfunc Validate(",
+		"func (c *Config) Validate(", "// Validate is a method.\nfunc (c *Config) Validate(",
+	).Replace(sameNameSrc)
+	dir := writeTree(t, map[string]string{"config.go": src})
+	stdout, _, _ := run(t, dir, "-check", "-quiet-success", "-treat-placeholders-as-missing",
+		"-func-format", "// %s is a function.", "-method-format", "// %s is a method.")
+	for _, want := range []string{
+		"func Validate has a placeholder godoc",
+		"method Config.Validate has a placeholder godoc",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("findings lack %q:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestKindFormatInvalid(t *testing.T) {
+	dir := writeTree(t, map[string]string{"config.go": sameNameSrc})
+	_, stderr, code := run(t, dir, "-method-format", "// {{.Nope}}")
+	if code == 0 || !strings.Contains(stderr, "invalid method comment format") {
+		t.Errorf("exit status %d, stderr %q, want an invalid method comment format error", code, stderr)
+	}
+}
+
+// blankReceivers are methods whose receivers are named _, next to the unnamed ones of example/store_methods.go.
+const blankReceivers = `package example
+
+func (_ *MemStore) Len() int {
+	return 0
+}
+
+func (_ MemStore) Kind() string {
+	return "mem"
+}
+`
+
+func TestUnnamedReceivers(t *testing.T) {
+	dir := copyExample(t, "store.go", "store_methods.go")
+	if err := os.WriteFile(filepath.Join(dir, "blank.go"), []byte(blankReceivers), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, _ := run(t, dir, "-check", "-quiet-success")
+	for _, want := range []string{"method MemStore.Close missing godoc", "method MemStore.Name missing godoc",
+		"method MemStore.Len missing godoc", "method MemStore.Kind missing godoc"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("findings lack %q:\n%s", want, stdout)
+		}
+	}
+	_, stderr, code := run(t, dir, "-quiet-success", "-auto-description", "-implements",
+		"-method-format", "// {{.Name}} is a method of {{.Receiver}}.")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	files := readTree(t, dir)
+	for _, want := range []string{
+		"// Get implements Store.Get.\nfunc (m *MemStore) Get(",
+		"// Close closes the mem Store and returns any error.\nfunc (*MemStore) Close() error",
+		"// Name is a method of MemStore.\nfunc (MemStore) Name() string",
+		"// Len is a method of *MemStore.\nfunc (_ *MemStore) Len() int",
+		"// Kind is a method of MemStore.\nfunc (_ MemStore) Kind() string",
+	} {
+		if !strings.Contains(files["store_methods.go"]+files["blank.go"], want) {
+			t.Errorf("repaired files lack %q:\n%s%s", want, files["store_methods.go"], files["blank.go"])
+		}
+	}
+}
+
+func TestParseFormatComment(t *testing.T) {
+	tests := []struct {
+		format string
+		ok     bool
+	}{
+		{"// %s missing godoc.", true},
+		{"/* %s missing godoc. */", true},
+		{"/*\n%s missing godoc.\n*/", true},
+		{"// {{.Name}} is a {{.Kind}}.\n//\n// {{range .Params}}{{.Name}} {{end}}", true},
+		{"Doc for %s", false},
+		{"{{.Name}} is a {{.Kind}}.", false},
+		{"// %s\nvar x int", false},
+		{"/* %s missing godoc.", false},
+		{"/* %s */ var x int", false},
+	}
+	for _, tt := range tests {
+		_, err := parseFormat(tt.format)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseFormat(%q) = %v, want ok %v", tt.format, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateFormatNotComment(t *testing.T) {
+	stdout, _, code := run(t, t.TempDir(), "-validate-format", "Doc for %s")
+	if code == 0 || !strings.Contains(stdout, `renders "Doc for Name", which is not a // or /* */ comment`) {
+		t.Errorf("exit status %d, output %q, want the format rejected", code, stdout)
+	}
+}
+
+func TestGenericReceivers(t *testing.T) {
+	src := "package p\n\ntype Stack[T any] struct{}\n\nfunc (s *Stack[T]) Push(v T) {}\n\ntype Pair[K comparable, V any] struct{}\n\n" +
+		"func (p Pair[K, V]) Key() K { var k K; return k }\n\nfunc (*Stack[_]) Len() int { return 0 }\n"
+	dir := writeTree(t, map[string]string{"a.go": src})
+	stdout, _, _ := run(t, dir, "-check")
+	for _, want := range []string{"method Stack.Push missing godoc", "method Pair.Key missing godoc", "method Stack.Len missing godoc"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("findings lack %q:\n%s", want, stdout)
+		}
+	}
+	if _, stderr, code := run(t, dir, "-method-format", "// {{.Name}} is a method of {{.Receiver}}."); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["a.go"]
+	for _, want := range []string{
+		"// Push is a method of *Stack[T].\n",
+		"// Key is a method of Pair[K, V].\n",
+		"// Len is a method of *Stack[_].\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatMap(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"api/a.go":       "package api\n\nfunc A() {}\n",
+		"store/b_api.go": "package store\n\nfunc B() {}\n",
+		"store/c.go":     "package store\n\nfunc C() {}\n",
+	})
+	if _, stderr, code := run(t, dir, "-format-map", "api/*.go=// {{.Name}} (public API).",
+		"-format-map", "*_api.go=// %s is part of the API.", "-format-map", "store/*.go=// %s is unused."); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	files := readTree(t, dir)
+	for name, want := range map[string]string{
+		"api/a.go": "// A (public API).\n",
+		// the first matching glob wins, globs without a slash match the file name
+		"store/b_api.go": "// B is part of the API.\n",
+		"store/c.go":     "// C is unused.\n",
+	} {
+		if !strings.Contains(files[name], want) {
+			t.Errorf("%s lacks %q:\n%s", name, want, files[name])
+		}
+	}
+	if _, stderr, code := run(t, dir, "-format-map", "api/*.go"); code == 0 || !strings.Contains(stderr, "is not of the form glob=format") {
+		t.Errorf("exit status %d, stderr %q, want the map rejected", code, stderr)
+	}
+}
+
+func TestInternalFormat(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.go":                 "package p\n\nfunc A() {}\n",
+		"internal/b.go":        "package internal\n\nfunc B() {}\n",
+		"pkg/internal/db/c.go": "package db\n\nfunc C() {}\n",
+		"internalize/d.go":     "package internalize\n\nfunc D() {}\n",
+		"internal/mapped/e.go": "package mapped\n\nfunc E() {}\n",
+	})
+	_, stderr, code := run(t, dir, "-internal-format", "// %s is internal.", "-format-map", "internal/mapped/*.go=// %s is mapped.")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	files := readTree(t, dir)
+	for name, want := range map[string]string{
+		"a.go":                 "// A missing godoc.\n",
+		"internal/b.go":        "// B is internal.\n",
+		"pkg/internal/db/c.go": "// C is internal.\n",
+		// only a whole internal path segment counts
+		"internalize/d.go": "// D missing godoc.\n",
+		// -format-map takes precedence
+		"internal/mapped/e.go": "// E is mapped.\n",
+	} {
+		if !strings.Contains(files[name], want) {
+			t.Errorf("%s lacks %q:\n%s", name, want, files[name])
+		}
+	}
+}
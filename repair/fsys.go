@@ -0,0 +1,167 @@
+package repair
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Writer persists the files changed by a run, e.g. to an in-memory map, a staging area or a shadow directory.
+// Write gives the file by its slash separated path relative to the directory repaired, with its mode.
+type Writer interface {
+	Write(path string, content []byte, mode fs.FileMode) error
+}
+
+// writeFS is a file system which can write instrumented files back.
+type writeFS interface {
+	fs.FS
+	Writer
+}
+
+// chmodFS is a writable file system whose files can be made writable or read-only.
+type chmodFS interface {
+	writeFS
+	Chmod(name string, perm fs.FileMode) error
+}
+
+// removeFS is a writable file system whose files can be removed, e.g. those created by a run rolled back.
+type removeFS interface {
+	writeFS
+	Remove(name string) error
+}
+
+// outputWriter writes the changes of the run, nil writes them back to the file system they were read from.
+var outputWriter Writer
+
+// dirFS is a writable file system rooted at a directory of the host.
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+func newDirFS(dir string) dirFS {
+	return dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+// Write writes data to the file name relative to the root of the file system.
+// The data is written to a temporary file renamed over name, so an interrupted run never leaves
+// a partially written file behind. A symlink is kept, the file it points to is replaced. The temporary
+// file is given the owner of the file replaced, when that is not possible, e.g. for a file of another user,
+// the file is written in place instead so it keeps its owner.
+func (d dirFS) Write(name string, data []byte, perm fs.FileMode) error {
+	fileName := filepath.Join(d.dir, filepath.FromSlash(name))
+	target, err := filepath.EvalSymlinks(fileName)
+	if err == nil {
+		fileName = target
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	info, err := os.Stat(fileName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if info != nil {
+		if uid, gid, ok := fileOwner(info); ok {
+			if err := tmp.Chown(uid, gid); err != nil {
+				tmp.Close()
+				return os.WriteFile(fileName, data, perm)
+			}
+		}
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fileName)
+}
+
+// Chmod changes the mode of the file name relative to the root of the file system, see os.Chmod.
+func (d dirFS) Chmod(name string, perm fs.FileMode) error {
+	return os.Chmod(filepath.Join(d.dir, filepath.FromSlash(name)), perm)
+}
+
+// Remove removes the file name relative to the root of the file system, see os.Remove.
+func (d dirFS) Remove(name string) error {
+	return os.Remove(filepath.Join(d.dir, filepath.FromSlash(name)))
+}
+
+// shadowDir writes files below a directory of the host, creating their parent directories,
+// e.g. to keep the sources unchanged with --out-dir.
+type shadowDir struct {
+	dir string
+}
+
+// Write writes data to the file name relative to the directory, atomically as dirFS does.
+func (s shadowDir) Write(name string, data []byte, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Join(s.dir, filepath.FromSlash(path.Dir(name))), 0755); err != nil {
+		return err
+	}
+	return newDirFS(s.dir).Write(name, data, perm)
+}
+
+// parsedHashes holds the content hash of every file parsed by parseDir, by file name, so a file
+// modified before its changes are written is detected.
+var parsedHashes = make(map[string]string)
+
+// parseDir parses the go files of dir in fsys accepted by filter, see parser.ParseDir.
+func parseDir(fset *token.FileSet, fsys fs.FS, dir string, filter func(fs.DirEntry) bool) (map[string]*ast.Package, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := make(map[string]*ast.Package)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") || !filter(entry) {
+			continue
+		}
+		fileName := path.Join(dir, entry.Name())
+		src, err := fs.ReadFile(fsys, fileName)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading file %s: %v", fileName, err)
+		}
+		parsedHashes[fileName] = contentHash(src)
+		file, err := parser.ParseFile(fset, fileName, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		name := file.Name.Name
+		pkg, ok := pkgs[name]
+		if !ok {
+			pkg = &ast.Package{Name: name, Files: make(map[string]*ast.File)}
+			pkgs[name] = pkg
+		}
+		pkg.Files[fileName] = file
+	}
+	return pkgs, nil
+}
+
+// sortedFiles returns the file names of pkg in order, so files are processed the same way on every run.
+func sortedFiles(pkg *ast.Package) []string {
+	fileNames := make([]string, 0, len(pkg.Files))
+	for fileName := range pkg.Files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	return fileNames
+}
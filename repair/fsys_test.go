@@ -0,0 +1,55 @@
+package repair
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteKeepsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "shared", "a.go")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "a.go")
+	if err := os.Symlink(filepath.Join("shared", "a.go"), link); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+	if err := newDirFS(dir).Write("a.go", []byte("// Package a is repaired.\npackage a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("a.go is no longer a symlink: %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "// Package a is repaired.\npackage a\n" {
+		t.Errorf("the target of the symlink was not written: %q, %v", data, err)
+	}
+}
+
+func TestWriteKeepsOwner(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing the owner of a file needs root")
+	}
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(fileName, []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(fileName, 1234, 5678); err != nil {
+		t.Fatal(err)
+	}
+	if err := newDirFS(dir).Write("a.go", []byte("// Package a is repaired.\npackage a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid, gid, _ := fileOwner(info); uid != 1234 || gid != 5678 {
+		t.Errorf("owner %d:%d, want 1234:5678", uid, gid)
+	}
+}
@@ -0,0 +1,41 @@
+package repair
+
+import (
+	"fmt"
+	"strings"
+)
+
+// changedFiles holds the .go files changed since HEAD forked from --diff-branch, relative to the code path,
+// nil unless the flag is given.
+var changedFiles map[string]bool
+
+// diffBranchFiles returns the .go files under dir changed since HEAD forked from branch: those changed
+// by the commits on HEAD, as listed by git diff --name-only branch...HEAD, the staged and unstaged changes
+// and the new files not ignored. Deleted files are left out.
+func diffBranchFiles(dir, branch string) (map[string]bool, error) {
+	base, err := git(dir, "merge-base", branch, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed finding where HEAD forked from %s: %v", branch, err)
+	}
+	// the diff of the fork point with the working tree covers the commits, the index and the working tree
+	out, err := git(dir, "diff", "--name-only", "--relative", "--diff-filter=d", strings.TrimSpace(string(base)), "--", "*.go")
+	if err != nil {
+		return nil, fmt.Errorf("failed listing files changed relative to %s: %v", branch, err)
+	}
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard", "--", "*.go")
+	if err != nil {
+		return nil, fmt.Errorf("failed listing new files: %v", err)
+	}
+	files := make(map[string]bool)
+	for _, line := range strings.Split(string(out)+string(untracked), "\n") {
+		if line != "" {
+			files[line] = true
+		}
+	}
+	return files, nil
+}
+
+// changedFilter skips files not changed relative to --diff-branch.
+func changedFilter(fileName string) bool {
+	return changedFiles == nil || changedFiles[fileName]
+}
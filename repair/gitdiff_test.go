@@ -0,0 +1,64 @@
+package repair
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDiffBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	files := map[string]string{
+		"committed.go": "package p\n\nfunc Committed() {}\n",
+		"staged.go":    "package p\n\nfunc Staged() {}\n",
+		"unstaged.go":  "package p\n\nfunc Unstaged() {}\n",
+		"same.go":      "package p\n\nfunc Same() {}\n",
+	}
+	dir := writeTree(t, files)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	edit := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(files[name]+"\nconst X"+strings.TrimSuffix(name, ".go")+" = 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "-q")
+	git("checkout", "-q", "-b", "main")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	git("checkout", "-q", "-b", "feature")
+	edit("committed.go")
+	git("commit", "-q", "-am", "change")
+	edit("staged.go")
+	git("add", "staged.go")
+	edit("unstaged.go")
+	if err := os.WriteFile(filepath.Join(dir, "new.go"), []byte("package p\n\nfunc New() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before := readTree(t, dir)
+	if _, stderr, code := run(t, dir, "-diff-branch", "main"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	var changed []string
+	for _, name := range modifiedFiles(before, readTree(t, dir)) {
+		if !strings.HasPrefix(name, ".git/") {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	if want := []string{"committed.go", "new.go", "staged.go", "unstaged.go"}; strings.Join(changed, " ") != strings.Join(want, " ") {
+		t.Errorf("changed %v, want %v", changed, want)
+	}
+}
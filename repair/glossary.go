@@ -0,0 +1,86 @@
+package repair
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// glossary maps names to hand-written descriptions used instead of generated ones.
+var glossary map[string]string
+
+// glossaryUsed records the glossary keys which matched a declaration, so unused ones can be reported.
+var glossaryUsed = make(map[string]bool)
+
+// loadGlossary reads a YAML file mapping names to descriptions, e.g. `store.Get: fetches the value of key.`
+func loadGlossary(fileName string) (map[string]string, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading glossary %s: %v", fileName, err)
+	}
+	var m map[string]string
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed parsing glossary %s: %v", fileName, err)
+	}
+	for key, desc := range m {
+		if _, err := path.Match(key, ""); err != nil {
+			return nil, fmt.Errorf("invalid glossary pattern %q: %v", key, err)
+		}
+		if strings.TrimSpace(desc) == "" {
+			return nil, fmt.Errorf("empty glossary description for %q", key)
+		}
+	}
+	return m, nil
+}
+
+// glossaryDescription returns the description of d from the glossary.
+// Keys are tried in order of precedence: the name qualified by its package, e.g. "store.Client.Close",
+// the name within its package, e.g. "Client.Close" or "Get", then wildcard patterns such as "*.Close"
+// matched against both, the longest pattern first.
+func glossaryDescription(d declaration) (string, bool) {
+	symbol := d.symbol()
+	for _, key := range []string{d.pkg + "." + symbol, symbol} {
+		if desc, ok := glossary[key]; ok {
+			glossaryUsed[key] = true
+			return desc, true
+		}
+	}
+	var patterns []string
+	for key := range glossary {
+		if !strings.ContainsAny(key, "*?[") {
+			continue
+		}
+		if ok, _ := path.Match(key, symbol); ok {
+			patterns = append(patterns, key)
+		} else if ok, _ := path.Match(key, d.pkg+"."+symbol); ok {
+			patterns = append(patterns, key)
+		}
+	}
+	if len(patterns) == 0 {
+		return "", false
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	glossaryUsed[patterns[0]] = true
+	return glossary[patterns[0]], true
+}
+
+// unusedGlossary returns the glossary keys which matched no declaration, likely typos.
+func unusedGlossary() []string {
+	var keys []string
+	for key := range glossary {
+		if !glossaryUsed[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
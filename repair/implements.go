@@ -0,0 +1,245 @@
+package repair
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// implementation is the documented interface method a method implements.
+type implementation struct {
+	// iface is the name of the interface, qualified by the package name when it is declared in another package.
+	iface string
+	// doc is the comment of the interface method.
+	doc []string
+}
+
+// implementations maps the methods of the package being repaired, e.g. "MemStore.Get",
+// to the interface method they implement.
+var implementations map[string]implementation
+
+// moduleInterface is an exported interface declared in a package of the module.
+type moduleInterface struct {
+	// dir is the directory of the package in the file system walked.
+	dir string
+	// pkg and importPath are the name and the import path of the package, the path is empty when unknown.
+	pkg        string
+	importPath string
+	name       string
+	iface      *ast.InterfaceType
+}
+
+// moduleInterfaces holds the interfaces of each module by the directory of its root, collected once per run.
+var moduleInterfaces = make(map[string][]moduleInterface)
+
+// collectImplementations fills implementations from the symbol table of the package of dir in fsys.
+// Interfaces are matched by method names and signatures as written in source, as there is no type
+// information: interfaces with embedded interfaces are not considered, and the types of an interface
+// of another package are taken for those of its package name, or of the name the package imports it by.
+// The interfaces of the package come first, then those of the other packages of the module by their
+// distance in the directory tree, e.g. a sibling package before a cousin, and by name.
+func collectImplementations(fsys fs.FS, dir string, s packageSymbols) {
+	implementations = make(map[string]implementation)
+	names := make([]string, 0, len(s.interfaces))
+	for name := range s.interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		addImplementations(s, name, s.interfaces[name], "")
+	}
+	candidates := append([]moduleInterface(nil), interfacesOf(fsys, dir)...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		di, dj := dirDistance(dir, candidates[i].dir), dirDistance(dir, candidates[j].dir)
+		if di != dj {
+			return di < dj
+		}
+		if candidates[i].dir != candidates[j].dir {
+			return candidates[i].dir < candidates[j].dir
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	for _, c := range candidates {
+		if c.dir == dir {
+			continue
+		}
+		qualifier := c.pkg
+		if name, ok := s.importPaths[c.importPath]; ok && c.importPath != "" {
+			qualifier = name
+		}
+		addImplementations(s, qualifier+"."+c.name, c.iface, qualifier)
+	}
+}
+
+// addImplementations records the documented methods of iface, named name, for the types of s implementing it.
+// The types in the signatures of iface are qualified with qualifier unless it is empty.
+func addImplementations(s packageSymbols, name string, iface *ast.InterfaceType, qualifier string) {
+	recvs := make([]string, 0, len(s.methods))
+	for recv := range s.methods {
+		recvs = append(recvs, recv)
+	}
+	sort.Strings(recvs)
+	for _, recv := range recvs {
+		if recv == name || !implements(s.methods[recv], iface, qualifier) {
+			continue
+		}
+		for _, field := range iface.Methods.List {
+			if field.Doc == nil {
+				continue
+			}
+			symbol := recv + "." + field.Names[0].Name
+			if _, ok := implementations[symbol]; ok {
+				continue
+			}
+			var doc []string
+			for _, c := range field.Doc.List {
+				doc = append(doc, c.Text)
+			}
+			implementations[symbol] = implementation{iface: name, doc: doc}
+		}
+	}
+}
+
+// interfacesOf returns the exported interfaces of the packages of the module dir belongs to, leaving out
+// main packages, test files, vendor and testdata directories and nested modules. Files which do not parse
+// are skipped.
+func interfacesOf(fsys fs.FS, dir string) []moduleInterface {
+	root, modPath := owningModule(fsys, dir)
+	known := root != ""
+	if !known {
+		// the module lies above fsys or there is none, the import paths of its packages are unknown
+		root = "."
+	}
+	if list, ok := moduleInterfaces[root]; ok {
+		return list
+	}
+	var list []moduleInterface
+	_ = fs.WalkDir(fsys, root, func(fileName string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if fileName != root && (entry.Name() == "vendor" || entry.Name() == "testdata" || modulePath(fsys, fileName) != "") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(fileName, ".go") || strings.HasSuffix(fileName, "_test.go") {
+			return nil
+		}
+		src, err := fs.ReadFile(fsys, fileName)
+		if err != nil {
+			return nil
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), fileName, src, parser.ParseComments)
+		if err != nil || file.Name.Name == "main" {
+			if err != nil && verbose {
+				log.Printf("Skipping file %s looking for interfaces: %v", fileName, err)
+			}
+			return nil
+		}
+		pkgDir := path.Dir(fileName)
+		importPath := ""
+		if known {
+			rel := strings.TrimPrefix(strings.TrimPrefix(pkgDir, root), "/")
+			if root == "." {
+				rel = pkgDir
+			}
+			importPath = path.Join(modPath, rel)
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok || !token.IsExported(ts.Name.Name) {
+					continue
+				}
+				if iface, ok := ts.Type.(*ast.InterfaceType); ok {
+					list = append(list, moduleInterface{dir: pkgDir, pkg: file.Name.Name, importPath: importPath, name: ts.Name.Name, iface: iface})
+				}
+			}
+		}
+		return nil
+	})
+	moduleInterfaces[root] = list
+	return list
+}
+
+// dirDistance returns the number of directories between the slash separated directories a and b
+// through their closest common parent.
+func dirDistance(a, b string) int {
+	elements := func(dir string) []string {
+		if dir = path.Clean(dir); dir == "." {
+			return nil
+		}
+		return strings.Split(dir, "/")
+	}
+	as, bs := elements(a), elements(b)
+	common := 0
+	for common < len(as) && common < len(bs) && as[common] == bs[common] {
+		common++
+	}
+	return len(as) + len(bs) - 2*common
+}
+
+// implements reports whether the method set, mapping names to signatures, has every method of iface,
+// the types of iface being qualified with qualifier unless it is empty.
+func implements(set map[string]string, iface *ast.InterfaceType, qualifier string) bool {
+	if iface.Methods == nil || len(iface.Methods.List) == 0 {
+		return false
+	}
+	for _, field := range iface.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			// embedded interfaces and constraints cannot be resolved without type information
+			return false
+		}
+		if sig, ok := set[field.Names[0].Name]; !ok || sig != qualify(signature(fn), qualifier) {
+			return false
+		}
+	}
+	return true
+}
+
+// signature renders the parameter and result types of fn without names, e.g. "(string, int) (error)".
+func signature(fn *ast.FuncType) string {
+	return "(" + strings.Join(astFieldTypes(fn.Params), ", ") + ") (" + strings.Join(astFieldTypes(fn.Results), ", ") + ")"
+}
+
+// exportedIdent matches the exported identifiers of a type expression which are not qualified by a package.
+var exportedIdent = regexp.MustCompile(`(^|[^.\w])([\p{Lu}]\w*)`)
+
+// qualify returns the types of the signature sig declared in another package as referred to from the package
+// importing it by qualifier, e.g. "(Value) (error)" as "(store.Value) (error)", sig when qualifier is empty.
+func qualify(sig, qualifier string) string {
+	if qualifier == "" {
+		return sig
+	}
+	return exportedIdent.ReplaceAllString(sig, "${1}"+qualifier+".${2}")
+}
+
+// implementationDoc returns the doc of a method d implementing a documented interface method,
+// "// Get implements Store.Get." or with --copy-interface-docs the comment of the interface method.
+func implementationDoc(d declaration) ([]string, bool) {
+	if d.kind != kindMethod {
+		return nil, false
+	}
+	impl, ok := implementations[d.symbol()]
+	if !ok {
+		return nil, false
+	}
+	if copyInterfaceDocs {
+		return impl.doc, true
+	}
+	return []string{fmt.Sprintf("// %s implements %s.%s.", d.name, impl.iface, d.name)}, true
+}
@@ -0,0 +1,96 @@
+package repair
+
+import (
+	"strings"
+	"testing"
+)
+
+// implementsFiles is a module whose mem package implements interfaces of its own, of a subpackage and of a sibling.
+var implementsFiles = map[string]string{
+	"store/store.go": `package store
+
+type Value string
+
+type Finder interface {
+	// Find returns the value stored for key.
+	Find(key string) (Value, error)
+}
+
+type Getter interface {
+	// Get returns the value stored for key.
+	Get(key string) (string, error)
+}
+
+type Closer interface {
+	// Close closes the store.
+	Close() error
+}
+`,
+	"mem/cache/cache.go": `package cache
+
+type Cache interface {
+	// Get returns the cached value of key.
+	Get(key string) (string, error)
+}
+`,
+	"mem/mem.go": `package mem
+
+import st "fixture/store"
+
+type Closer interface {
+	// Close releases the resources held.
+	Close() error
+}
+
+type MemStore struct{}
+
+func (m *MemStore) Find(key string) (st.Value, error) {
+	return "", nil
+}
+
+func (m *MemStore) Get(key string) (string, error) {
+	return "", nil
+}
+
+func (m *MemStore) Close() error {
+	return nil
+}
+`,
+}
+
+func TestImplementsModuleWide(t *testing.T) {
+	dir := writeTree(t, implementsFiles)
+	if _, stderr, code := run(t, dir, "-quiet-success", "-implements"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["mem/mem.go"]
+	for _, want := range []string{
+		// the interface of the package comes first
+		"// Close implements Closer.Close.\nfunc (m *MemStore) Close()",
+		// then the closest package, the subpackage before the sibling
+		"// Get implements cache.Cache.Get.\nfunc (m *MemStore) Get(",
+		// types of other packages are matched by the name the package is imported by
+		"// Find implements st.Finder.Find.\nfunc (m *MemStore) Find(",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("repaired mem.go lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestDirDistance(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"mem", "mem", 0},
+		{"mem", "mem/cache", 1},
+		{"mem", "store", 2},
+		{".", "store", 1},
+		{"a/b/c", "a/d", 3},
+	} {
+		if got := dirDistance(c.a, c.b); got != c.want {
+			t.Errorf("dirDistance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
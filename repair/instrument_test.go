@@ -0,0 +1,340 @@
+package repair
+
+import (
+	"go/format"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// reproducibleFiles is a package of several files, with types whose methods are spread across them, and a subpackage.
+var reproducibleFiles = map[string]string{
+	"a.go":     "package repro\n\ntype Store struct{}\n\nfunc (s *Store) Get(key string) string { return key }\n\nconst A, B = 1, 2\n",
+	"b.go":     "package repro\n\nfunc (s *Store) Put(key, value string) {}\n\ntype Reader interface {\n\tRead() string\n}\n",
+	"c.go":     "package repro\n\nvar (\n\tX = 1\n\tY = 2\n)\n\nfunc New() *Store { return nil }\n",
+	"sub/d.go": "package sub\n\nfunc D() {}\n\ntype T int\n",
+}
+
+// repairCopy repairs a fresh copy of reproducibleFiles and returns the repaired files and the manifest
+// without the time of the run.
+func repairCopy(t *testing.T) (map[string]string, string) {
+	t.Helper()
+	files := make(map[string]string)
+	for name, content := range reproducibleFiles {
+		files[name] = content
+	}
+	dir := writeTree(t, files)
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+	if _, stderr, code := run(t, dir, "-auto-description", "-manifest", manifest); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readTree(t, dir), regexp.MustCompile(`"time": "[^"]*"`).ReplaceAllString(string(data), "")
+}
+
+func TestInstrumentReproducible(t *testing.T) {
+	firstFiles, firstManifest := repairCopy(t)
+	if firstFiles["a.go"] == reproducibleFiles["a.go"] {
+		t.Fatal("the run changed nothing")
+	}
+	// the files of each copy are created in another order, which does not change the result
+	for i := 0; i < 3; i++ {
+		files, manifest := repairCopy(t)
+		if !reflect.DeepEqual(files, firstFiles) {
+			t.Errorf("runs over the same input gave different files:\n%q\n---\n%q", files, firstFiles)
+		}
+		if manifest != firstManifest {
+			t.Errorf("runs over the same input gave different manifests:\n%s\n---\n%s", manifest, firstManifest)
+		}
+	}
+	// repairing the repaired files again leaves them as they are
+	dir := writeTree(t, firstFiles)
+	run(t, dir, "-auto-description")
+	if files := readTree(t, dir); !reflect.DeepEqual(files, firstFiles) {
+		t.Errorf("repairing the repaired files changed them:\n%q\n---\n%q", files, firstFiles)
+	}
+}
+
+func TestTrailingCommentsKept(t *testing.T) {
+	dir := copyExample(t, "limits.go")
+	if _, stderr, code := run(t, dir, "-quiet-success"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	want := `package example
+
+// MaxRetries missing godoc.
+const MaxRetries = 3 // retries before giving up
+
+const (
+	// MinPort missing godoc.
+	MinPort = 1024 // first unprivileged port
+	// MaxPort missing godoc.
+	MaxPort = 65535 /* highest port */
+)
+
+// Limits missing godoc.
+type Limits struct {
+	Retries int // zero means MaxRetries
+}
+`
+	if got := readTree(t, dir)["limits.go"]; got != want {
+		t.Errorf("repaired limits.go:\n%s\nwant\n%s", got, want)
+	}
+	// the trailing comments are not taken for docs, the declarations are reported missing one
+	stdout, _, _ := run(t, copyExample(t, "limits.go"), "-check", "-quiet-success")
+	for _, name := range []string{"const MaxRetries", "const MinPort", "const MaxPort", "type Limits"} {
+		if !strings.Contains(stdout, name+" missing godoc") {
+			t.Errorf("findings lack %s:\n%s", name, stdout)
+		}
+	}
+}
+
+// The inserted doc is attached to its declaration, a detached comment and the blank lines
+// between specs stay where they were.
+func TestInsertedDocAttached(t *testing.T) {
+	src := "package p\n\nfunc A() {}\n\n// detached\n\nfunc B() {}\n\n/* block */\n\nfunc C() {}\n\nvar (\n\tX = 1\n\n\tY = 2\n)\n"
+	dir := writeTree(t, map[string]string{"a.go": src})
+	if _, stderr, code := run(t, dir); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	want := "package p\n\n// A missing godoc.\nfunc A() {}\n\n// detached\n\n// B missing godoc.\nfunc B() {}\n\n" +
+		"/* block */\n\n// C missing godoc.\nfunc C() {}\n\nvar (\n\t// X missing godoc.\n\tX = 1\n\n\t// Y missing godoc.\n\tY = 2\n)\n"
+	if got := readTree(t, dir)["a.go"]; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	// go doc takes the inserted comments for the docs
+	if stdout, _, code := run(t, dir, "-check"); code != 0 {
+		t.Errorf("exit status %d, findings:\n%s", code, stdout)
+	}
+}
+
+// A block comment on the line of a declaration is not its doc for go doc, it is moved above it once.
+func TestInlineBlockDoc(t *testing.T) {
+	src := "package p\n\n/* GetUser does x */ func GetUser() {}\n\n/* not a doc */ func Close() {}\n"
+	dir := writeTree(t, map[string]string{"a.go": src})
+	if stdout, _, _ := run(t, dir, "-check"); !strings.Contains(stdout, "a.go:3:22: func GetUser missing godoc") {
+		t.Errorf("the inline block comment was taken for a doc:\n%s", stdout)
+	}
+	want := "package p\n\n// GetUser does x\nfunc GetUser() {}\n\n// Close not a doc\nfunc Close() {}\n"
+	for i := 0; i < 2; i++ {
+		if _, stderr, code := run(t, dir); code != 0 {
+			t.Fatalf("exit status %d: %s", code, stderr)
+		}
+		if got := readTree(t, dir)["a.go"]; got != want {
+			t.Errorf("run %d: got\n%s\nwant\n%s", i+1, got, want)
+		}
+	}
+}
+
+func TestCollapseBlankCommentLines(t *testing.T) {
+	src := "package p\n\n// Run runs.\n//\n//\n//\n// More.\nfunc Run() {}\n\n// Stop stops.\n//\n//\n// More.\nfunc stop() {}\n\n/* Open opens.\n\n\nMore. */\nfunc Open() {}\n"
+	dir := writeTree(t, map[string]string{"a.go": src})
+	run(t, dir)
+	if got := readTree(t, dir)["a.go"]; got != src {
+		t.Errorf("blank lines were collapsed without the flag:\n%s", got)
+	}
+	if _, stderr, code := run(t, dir, "-collapse-multiple-blank-comment-lines"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	// the doc of an unexported declaration and block comments are left alone
+	want := strings.Replace(src, "// Run runs.\n//\n//\n//\n", "// Run runs.\n//\n", 1)
+	if got := readTree(t, dir)["a.go"]; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLineDirectives(t *testing.T) {
+	src := "package p\n\n//line gen.y:10\nfunc Gen() {}\n"
+	dir := writeTree(t, map[string]string{"a.go": src})
+	if stdout, _, _ := run(t, dir, "-check"); !strings.Contains(stdout, "a.go:4:1: func Gen missing godoc") {
+		t.Errorf("findings %q, want the physical position", stdout)
+	}
+	if stdout, _, _ := run(t, dir, "-check", "-line-directives"); !strings.Contains(stdout, "gen.y:10: func Gen missing godoc") {
+		t.Errorf("findings %q, want the position of the directive", stdout)
+	}
+	run(t, dir, "-skip-line-directives")
+	if got := readTree(t, dir)["a.go"]; got != src {
+		t.Errorf("file with a //line directive was repaired:\n%s", got)
+	}
+	// the directive keeps applying to the line below it
+	run(t, dir)
+	if got, want := readTree(t, dir)["a.go"], "package p\n\n// Gen missing godoc.\n//line gen.y:10\nfunc Gen() {}\n"; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestIncludeTestsSkipsTestingFuncs(t *testing.T) {
+	src := "package p\n\nimport \"testing\"\n\nfunc TestServer_Start(t *testing.T) {}\n\nfunc BenchmarkParse(b *testing.B) {}\n\n" +
+		"func FuzzDecode(f *testing.F) {}\n\nfunc ExampleStart() {}\n\nfunc Testify() {}\n\nfunc TestHelper(x int) {}\n"
+	dir := writeTree(t, map[string]string{"a.go": "package p\n\nfunc Start() {}\n", "a_test.go": src})
+	run(t, dir)
+	if got := readTree(t, dir)["a_test.go"]; got != src {
+		t.Errorf("test file was repaired without -include-tests:\n%s", got)
+	}
+	if _, stderr, code := run(t, dir, "-include-tests"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	// names merely starting like a test, or with another signature, are no testing functions
+	want := strings.NewReplacer("func Testify", "// Testify missing godoc.\nfunc Testify",
+		"func TestHelper", "// TestHelper missing godoc.\nfunc TestHelper").Replace(src)
+	if got := readTree(t, dir)["a_test.go"]; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStamp(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.go": "// Package p does things.\npackage p\n\nfunc Open() {}\n",
+		"b.go": "package p\n\n// Close closes.\nfunc Close() {}\n",
+	})
+	run(t, dir, "-stamp")
+	want := "// Package p does things.\npackage p\n\n// Code partially documented by godoc-repair.\n\n// Open missing godoc.\nfunc Open() {}\n"
+	files := readTree(t, dir)
+	if files["a.go"] != want {
+		t.Errorf("got\n%s\nwant\n%s", files["a.go"], want)
+	}
+	if strings.Contains(files["b.go"], "godoc-repair") {
+		t.Errorf("file left unchanged was stamped:\n%s", files["b.go"])
+	}
+	// a file repaired again is not stamped twice
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte(want+"\nfunc Run() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(t, dir, "-stamp")
+	if got := readTree(t, dir)["a.go"]; strings.Count(got, "Code partially documented") != 1 || !strings.Contains(got, "// Run missing godoc.") {
+		t.Errorf("second run:\n%s", got)
+	}
+}
+
+func TestOnlyKinds(t *testing.T) {
+	src := "package p\n\ntype T struct{}\n\nfunc F() {}\n\nfunc (T) M() {}\n\nconst C = 1\n\nvar V int\n"
+	tests := []struct {
+		kinds string
+		want  []string
+	}{
+		{"func,method", []string{"// F missing godoc.\nfunc F", "// M missing godoc.\nfunc (T) M"}},
+		{"type", []string{"// T missing godoc.\ntype T"}},
+		{"const,var", []string{"// C missing godoc.\nconst C", "// V missing godoc.\nvar V"}},
+		{"method, type", []string{"// T missing godoc.\ntype T", "// M missing godoc.\nfunc (T) M"}},
+	}
+	for _, tt := range tests {
+		dir := writeTree(t, map[string]string{"a.go": src})
+		if _, stderr, code := run(t, dir, "-only-kinds", tt.kinds); code != 0 {
+			t.Fatalf("-only-kinds %s: exit status %d: %s", tt.kinds, code, stderr)
+		}
+		got := readTree(t, dir)["a.go"]
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("-only-kinds %s: a.go lacks %q:\n%s", tt.kinds, want, got)
+			}
+		}
+		if n := strings.Count(got, "missing godoc"); n != len(tt.want) {
+			t.Errorf("-only-kinds %s: %d docs added, want %d:\n%s", tt.kinds, n, len(tt.want), got)
+		}
+	}
+	if _, stderr, code := run(t, t.TempDir(), "-only-kinds", "func,nope"); code == 0 || !strings.Contains(stderr, `unknown kind "nope"`) {
+		t.Errorf("exit status %d, stderr %q, want the kind rejected", code, stderr)
+	}
+}
+
+func TestMethodsExportedReceiversOnly(t *testing.T) {
+	src := "package p\n\ntype cache struct{}\n\nfunc (c *cache) Get() {}\n\ntype Cache struct{}\n\nfunc (c *Cache) Get() {}\n\n" +
+		"type list[T any] struct{}\n\nfunc (l list[T]) Len() int { return 0 }\n"
+	for _, only := range []bool{false, true} {
+		dir := writeTree(t, map[string]string{"a.go": src})
+		args := []string{"-check"}
+		if only {
+			args = append(args, "-methods-exported-receivers-only")
+		}
+		stdout, _, _ := run(t, dir, args...)
+		for symbol, found := range map[string]bool{"method Cache.Get": true, "method cache.Get": !only, "method list.Len": !only} {
+			if got := strings.Contains(stdout, symbol+" missing godoc"); got != found {
+				t.Errorf("-methods-exported-receivers-only=%v: %s reported %v, want %v:\n%s", only, symbol, got, found, stdout)
+			}
+		}
+	}
+}
+
+func TestMaxFileBytes(t *testing.T) {
+	small := "package p\n\nfunc A() {}\n"
+	// big is one byte over the limit of the size of small
+	big := "package p\n\nfunc B()  {}\n"
+	dir := writeTree(t, map[string]string{"small.go": small, "big.go": big})
+	limit := strconv.Itoa(len(small))
+	stdout, stderr, _ := run(t, dir, "-check", "-v", "-max-file-bytes", limit)
+	if !strings.Contains(stdout, "func A missing godoc") || strings.Contains(stdout, "func B") {
+		t.Errorf("findings %q, want only those of small.go", stdout)
+	}
+	if !strings.Contains(stderr, "Skipping file big.go of "+strconv.Itoa(len(big))+" bytes, larger than "+limit) {
+		t.Errorf("logs lack the skipped big.go:\n%s", stderr)
+	}
+	if _, stderr, code := run(t, dir, "-max-file-bytes", limit); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	files := readTree(t, dir)
+	if files["big.go"] != big || !strings.Contains(files["small.go"], "// A missing godoc.\n") {
+		t.Errorf("want only small.go repaired, got %q", files)
+	}
+}
+
+func TestMultiNameIotaSpecs(t *testing.T) {
+	src := `package p
+
+const (
+	A, B = iota, iota + 1
+	C, D
+	_, E = iota, iota + 1
+	f, g = iota, iota
+)
+
+const H, I = iota, 2
+
+var J, k = 1, 2
+`
+	dir := writeTree(t, map[string]string{"a.go": src})
+	stdout, _, _ := run(t, dir, "-check")
+	// a spec has a single doc for all its names, it is reported by its first exported name
+	for _, want := range []string{"const A missing godoc", "const C missing godoc", "const E missing godoc", "const H missing godoc", "var J missing godoc"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("findings lack %q:\n%s", want, stdout)
+		}
+	}
+	for _, name := range []string{"B", "D", "f", "I"} {
+		if strings.Contains(stdout, " "+name+" missing godoc") {
+			t.Errorf("%s is reported apart from the first name of its spec:\n%s", name, stdout)
+		}
+	}
+	if _, stderr, code := run(t, dir); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["a.go"]
+	formatted, err := format.Source([]byte(got))
+	if err != nil {
+		t.Fatalf("repaired file is not valid Go: %v\n%s", err, got)
+	}
+	if string(formatted) != got {
+		t.Errorf("repaired file is not gofmt'ed:\n%s", got)
+	}
+	for _, want := range []string{
+		"\t// A missing godoc.\n\tA, B = iota, iota + 1\n",
+		"\t// C missing godoc.\n\tC, D\n",
+		"\t// E missing godoc.\n\t_, E = iota, iota + 1\n",
+		"\tf, g = iota, iota\n",
+		"// H missing godoc.\nconst H, I = iota, 2\n",
+		"// J missing godoc.\nvar J, k = 1, 2\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+	if stdout, _, code := run(t, dir, "-check"); code != 0 {
+		t.Errorf("exit status %d after repairing, findings:\n%s", code, stdout)
+	}
+}
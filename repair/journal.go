@@ -0,0 +1,227 @@
+package repair
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxJournalRuns is how many runs are kept in the journal.
+const maxJournalRuns = 5
+
+// journal records the files changed by a run, so -undo can revert them.
+type journal struct {
+	Run string `json:"run"`
+	// Root is the absolute code path of the run.
+	Root  string        `json:"root"`
+	Files []journalFile `json:"files"`
+}
+
+// journalFile records how to revert the changes of a file.
+type journalFile struct {
+	// File is the absolute path of the file.
+	File string `json:"file"`
+	// Hash is the hash of the content written by the run, the file is only reverted while it still has it.
+	Hash string `json:"hash"`
+	// Created is set when the file did not exist before the run.
+	Created bool `json:"created,omitempty"`
+	// Edits turn the written content back into the original one.
+	Edits []planEdit `json:"edits"`
+}
+
+// undoFlag is the value of -undo, which may be given alone to revert the last run or with a run id.
+type undoFlag string
+
+func (u *undoFlag) String() string {
+	return string(*u)
+}
+
+func (u *undoFlag) Set(value string) error {
+	*u = undoFlag(value)
+	return nil
+}
+
+// IsBoolFlag lets -undo be given without a value.
+func (u *undoFlag) IsBoolFlag() bool {
+	return true
+}
+
+// journalDir returns the directory of the journal of the runs over root, the absolute code path, below the cache dir,
+// or the user cache directory without one. Each code path has a journal of its own, so -undo never reverts
+// a run over another tree.
+func journalDir(root string) (string, error) {
+	dir := cacheDir
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("failed locating the journal: %v", err)
+		}
+		dir = filepath.Join(userDir, "godoc-repair")
+	}
+	return filepath.Join(dir, "journal", contentHash([]byte(root))[:16]), nil
+}
+
+// journalRoot returns the absolute code path the journal is kept for.
+func journalRoot() (string, error) {
+	root, err := filepath.Abs(codePath)
+	if err != nil {
+		return "", fmt.Errorf("failed locating the code path %s: %v", codePath, err)
+	}
+	return root, nil
+}
+
+// journalEntry returns how to revert c once written, false if c is not a file of the host.
+func journalEntry(c change) (journalFile, bool, error) {
+	host, ok := c.fsys.(dirFS)
+	if !ok || outputWriter != nil {
+		return journalFile{}, false, nil
+	}
+	fileName, err := filepath.Abs(filepath.Join(host.dir, filepath.FromSlash(c.fileName)))
+	if err != nil {
+		return journalFile{}, false, err
+	}
+	src, err := os.ReadFile(fileName)
+	created := errors.Is(err, fs.ErrNotExist)
+	if err != nil && !created {
+		return journalFile{}, false, fmt.Errorf("failed reading file %s: %v", c.fileName, err)
+	}
+	return journalFile{
+		File:    fileName,
+		Hash:    contentHash(c.data),
+		Created: created,
+		Edits:   diffLines(strings.Split(string(c.data), "\n"), strings.Split(string(src), "\n")),
+	}, true, nil
+}
+
+// saveJournal writes the journal of a run changing files and drops the oldest runs beyond maxJournalRuns.
+func saveJournal(files []journalFile) error {
+	root, err := journalRoot()
+	if err != nil {
+		return err
+	}
+	dir, err := journalDir(root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed creating directory %s: %v", dir, err)
+	}
+	j := journal{Run: time.Now().UTC().Format("20060102T150405.000000000Z"), Root: root, Files: files}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed encoding journal: %v", err)
+	}
+	if err := newDirFS(dir).Write(j.Run+".json", append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed writing journal of run %s: %v", j.Run, err)
+	}
+	logProgress("Recorded run %s, revert it with -undo", j.Run)
+	runs, err := recordedRuns(dir)
+	if err != nil {
+		return err
+	}
+	for len(runs) > maxJournalRuns {
+		if err := os.Remove(filepath.Join(dir, runs[0]+".json")); err != nil {
+			return fmt.Errorf("failed removing journal of run %s: %v", runs[0], err)
+		}
+		runs = runs[1:]
+	}
+	return nil
+}
+
+// recordedRuns returns the ids of the runs in the journal of dir, the oldest first.
+func recordedRuns(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed reading journal %s: %v", dir, err)
+	}
+	var runs []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasSuffix(name, ".json") {
+			runs = append(runs, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// undo reverts the files changed by run over the code path, the last one when run is "true" as for -undo
+// without a value. Files modified since the run are reported and left as they are. The run is removed from the journal.
+func undo(run string) error {
+	root, err := journalRoot()
+	if err != nil {
+		return err
+	}
+	dir, err := journalDir(root)
+	if err != nil {
+		return err
+	}
+	if run == "true" {
+		runs, err := recordedRuns(dir)
+		if err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			return fmt.Errorf("no run over %s to undo", root)
+		}
+		run = runs[len(runs)-1]
+	}
+	fileName := filepath.Join(dir, run+".json")
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("run %s over %s is not in the journal %s", run, root, dir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed reading journal of run %s: %v", run, err)
+	}
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return fmt.Errorf("failed parsing journal of run %s: %v", run, err)
+	}
+	if j.Root != root {
+		return fmt.Errorf("run %s is a run over %s, not %s", run, j.Root, root)
+	}
+	reverted := 0
+	for _, f := range j.Files {
+		src, err := os.ReadFile(f.File)
+		if err != nil {
+			log.Printf("Skipping %s: %v", f.File, err)
+			continue
+		}
+		if contentHash(src) != f.Hash {
+			log.Printf("Skipping %s modified since run %s", f.File, run)
+			continue
+		}
+		if f.Created {
+			if err := os.Remove(f.File); err != nil {
+				return fmt.Errorf("failed removing file %s: %v", f.File, err)
+			}
+			reverted++
+			continue
+		}
+		info, err := os.Stat(f.File)
+		if err != nil {
+			return fmt.Errorf("failed opening file %s: %v", f.File, err)
+		}
+		lines := applyEdits(strings.Split(string(src), "\n"), f.Edits)
+		out := []byte(strings.Join(lines, "\n"))
+		if err := newDirFS(filepath.Dir(f.File)).Write(filepath.Base(f.File), out, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed writing file %s: %v", f.File, err)
+		}
+		reverted++
+	}
+	if err := os.Remove(fileName); err != nil {
+		return fmt.Errorf("failed removing journal of run %s: %v", run, err)
+	}
+	log.Printf("Reverted %d of %d files changed by run %s", reverted, len(j.Files), run)
+	return nil
+}
@@ -0,0 +1,175 @@
+package repair
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// undoFiles are sources whose exact bytes -undo has to restore: a block comment, a detached comment,
+// a trailing comment and a file without a final line feed.
+var undoFiles = map[string]string{
+	"a.go": "package undo\n\n/* Open opens */\nfunc Open() {}\n\n// detached\n\nfunc Close() {} // closes\n",
+	"b.go": "package undo\n\nconst (\n\tA = 1\n\tB = 2\n)\n\nfunc Run() {}",
+}
+
+// fix repairs the tree of dir and returns the id of the run recorded in the journal.
+func fix(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	_, stderr, code := run(t, dir, args...)
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	m := regexp.MustCompile(`Recorded run (\S+),`).FindStringSubmatch(stderr)
+	if m == nil {
+		t.Fatalf("no run recorded: %s", stderr)
+	}
+	return m[1]
+}
+
+func TestUndoRestoresBytes(t *testing.T) {
+	dir := writeTree(t, undoFiles)
+	before := readTree(t, dir)
+	fix(t, dir, "-auto-description")
+	if reflect.DeepEqual(readTree(t, dir), before) {
+		t.Fatal("the run changed nothing")
+	}
+	if _, stderr, code := run(t, dir, "-undo"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if after := readTree(t, dir); !reflect.DeepEqual(after, before) {
+		t.Errorf("undo did not restore the files:\n%q\nwant\n%q", after, before)
+	}
+	// the run is removed from the journal once reverted
+	if _, stderr, code := run(t, dir, "-undo"); code == 0 || !strings.Contains(stderr, "no run over") {
+		t.Errorf("exit status %d, stderr %q, want no run to undo", code, stderr)
+	}
+}
+
+func TestUndoCreatedFile(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": "package undo\n\nfunc Open() {}\n"})
+	before := readTree(t, dir)
+	fix(t, dir, "-examples")
+	if _, ok := readTree(t, dir)["example_test.go"]; !ok {
+		t.Fatal("no example_test.go was created")
+	}
+	run(t, dir, "-undo")
+	if after := readTree(t, dir); !reflect.DeepEqual(after, before) {
+		t.Errorf("undo did not remove the created file: %q", after)
+	}
+}
+
+func TestUndoSkipsModifiedFiles(t *testing.T) {
+	dir := writeTree(t, undoFiles)
+	before := readTree(t, dir)
+	fix(t, dir)
+	modified := readTree(t, dir)["a.go"] + "\n// edited after the run\n"
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte(modified), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, _ := run(t, dir, "-undo")
+	if !strings.Contains(stderr, "Skipping "+filepath.Join(dir, "a.go")+" modified since run") {
+		t.Errorf("modified file not reported: %s", stderr)
+	}
+	after := readTree(t, dir)
+	if after["a.go"] != modified {
+		t.Errorf("modified file was reverted: %q", after["a.go"])
+	}
+	if after["b.go"] != before["b.go"] {
+		t.Errorf("unmodified file was not reverted: %q", after["b.go"])
+	}
+}
+
+func TestUndoOnlyRunsOfCodePath(t *testing.T) {
+	first, second := writeTree(t, undoFiles), writeTree(t, undoFiles)
+	before := readTree(t, first)
+	firstRun := fix(t, first)
+	secondRun := fix(t, second)
+	fixed := readTree(t, second)
+
+	// the last run is that of the second tree, undo in the first reverts its own
+	run(t, first, "-undo")
+	if after := readTree(t, first); !reflect.DeepEqual(after, before) {
+		t.Errorf("undo did not revert the run over its code path: %q", after)
+	}
+	if after := readTree(t, second); !reflect.DeepEqual(after, fixed) {
+		t.Errorf("undo reverted the run over another tree: %q", after)
+	}
+	// a run of another tree is not found, also given by id or with -code-path
+	if _, stderr, code := run(t, first, "-undo="+secondRun); code == 0 || !strings.Contains(stderr, "is not in the journal") {
+		t.Errorf("exit status %d, stderr %q, want the run of another tree not to be found", code, stderr)
+	}
+	if _, stderr, code := run(t, first, "-code-path", second, "-undo="+firstRun); code == 0 {
+		t.Errorf("undo of run %s with the code path of another tree succeeded: %s", firstRun, stderr)
+	}
+	if _, stderr, code := run(t, first, "-code-path", second, "-undo"); code != 0 {
+		t.Errorf("exit status %d: %s", code, stderr)
+	}
+	if after := readTree(t, second); reflect.DeepEqual(after, fixed) {
+		t.Error("undo with -code-path did not revert the run over it")
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	planned, fixed := writeTree(t, undoFiles), writeTree(t, undoFiles)
+	before := readTree(t, planned)
+	plan := filepath.Join(t.TempDir(), "plan.json")
+	if _, stderr, code := run(t, planned, "-auto-description", "-plan", plan); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if after := readTree(t, planned); !reflect.DeepEqual(after, before) {
+		t.Errorf("planning changed files: %q", after)
+	}
+	fix(t, fixed, "-auto-description")
+	if _, stderr, code := run(t, planned, "-apply", plan); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if after, want := readTree(t, planned), readTree(t, fixed); !reflect.DeepEqual(after, want) {
+		t.Errorf("applied plan gives\n%q\nwant the files repaired in place\n%q", after, want)
+	}
+}
+
+func TestApplyRefusesModifiedFiles(t *testing.T) {
+	dir := writeTree(t, undoFiles)
+	plan := filepath.Join(t.TempDir(), "plan.json")
+	run(t, dir, "-plan", plan)
+	modified := undoFiles["b.go"] + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "b.go"), []byte(modified), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, code := run(t, dir, "-apply", plan)
+	if code == 0 || !strings.Contains(stderr, "b.go was modified") {
+		t.Errorf("exit status %d, stderr %q, want the plan refused", code, stderr)
+	}
+	after := readTree(t, dir)
+	if after["a.go"] != undoFiles["a.go"] || after["b.go"] != modified {
+		t.Errorf("refused plan changed files: %q", after)
+	}
+}
+
+func TestJournalWithoutCacheDir(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": "package undo\n\nfunc Open() {}\n"})
+	before := readTree(t, dir)
+	var stderr bytes.Buffer
+	cmd := command(dir)
+	cmd.Env = nil
+	for _, kv := range os.Environ() {
+		if name := kv[:strings.Index(kv, "=")+1]; name != "HOME=" && name != "XDG_CACHE_HOME=" && name != "LocalAppData=" {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+	if reflect.DeepEqual(readTree(t, dir), before) {
+		t.Error("the files were not repaired")
+	}
+	if !strings.Contains(stderr.String(), "Not recording the run") {
+		t.Errorf("the journal failure is not logged: %s", stderr.String())
+	}
+}
@@ -0,0 +1,112 @@
+package repair
+
+import (
+	"encoding/xml"
+	"io"
+	"path"
+	"sort"
+)
+
+// outputJUnit is the -output of -check printing the findings as JUnit XML.
+const outputJUnit = "junit"
+
+// checkedPackages records the packages checked by the run, so those without findings are
+// reported as passing test suites.
+var checkedPackages = make(map[string]bool)
+
+// recordChecked records the package named name of the file fileName as checked.
+func recordChecked(fileName, name string) {
+	checkedPackages[packagePath(path.Dir(fileName), name)] = true
+}
+
+// packagePath names the package name of the directory dir, relative to the code path, by its directory
+// unless the package is named otherwise, e.g. "api" for package api of api, "api/api_test" for its tests.
+func packagePath(dir, name string) string {
+	if path.Base(dir) == name {
+		return dir
+	}
+	return path.Join(dir, name)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+// printJUnit prints the findings as JUnit XML, a test suite for each checked package, sorted by name,
+// holding a failed test case for each finding in order of position.
+func printJUnit(out io.Writer) error {
+	sortFindings()
+	suites := make(map[string]*junitTestSuite)
+	suite := func(name string) *junitTestSuite {
+		if suites[name] == nil {
+			suites[name] = &junitTestSuite{Name: name}
+		}
+		return suites[name]
+	}
+	for name := range checkedPackages {
+		suite(name)
+	}
+	for _, f := range findings {
+		name := packagePath(path.Dir(f.pos.Filename), f.pkg)
+		s := suite(name)
+		s.Cases = append(s.Cases, junitTestCase{
+			Name:      f.symbol,
+			ClassName: name,
+			File:      f.pos.Filename,
+			Line:      f.pos.Line,
+			Failure:   &junitFailure{Message: f.String(), Type: f.rule},
+		})
+		s.Tests++
+		s.Failures++
+	}
+	names := make([]string, 0, len(suites))
+	for name := range suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	report := junitTestSuites{Suites: []junitTestSuite{}}
+	for _, name := range names {
+		s := suites[name]
+		// a package without findings passes as a whole
+		if s.Tests == 0 {
+			s.Tests = 1
+			s.Cases = []junitTestCase{{Name: "godoc", ClassName: name}}
+		}
+		report.Tests += s.Tests
+		report.Failures += s.Failures
+		report.Suites = append(report.Suites, *s)
+	}
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
@@ -0,0 +1,52 @@
+package repair
+
+import (
+	"context"
+	"io/fs"
+)
+
+// Directory repairs the docs of the exported declarations of the Go files in dir and below it,
+// as the command does with --code-path dir, and writes the changed files with w, or back in place
+// when w is nil. Files are only written once the whole tree was processed, so a canceled ctx
+// leaves them unchanged. Calls are serialized with those of Declaration and FS, the command line
+// flags do not apply to them.
+func Directory(ctx context.Context, dir string, opts Options, w Writer) error {
+	return repairTree(ctx, dir, newDirFS(dir), opts, w)
+}
+
+// FS repairs the docs of the exported declarations of the Go files in fsys as Directory does,
+// e.g. of an fstest.MapFS or a zip archive, and writes the changed files with w. When w is nil fsys
+// has to be a Writer itself to write them back, otherwise an error is returned as for a read-only
+// file system.
+func FS(ctx context.Context, fsys fs.FS, opts Options, w Writer) error {
+	// a read-only file system is repaired as well when the changes go elsewhere
+	if _, ok := fsys.(writeFS); !ok && w != nil {
+		fsys = writableFS{FS: fsys, Writer: w}
+	}
+	return repairTree(ctx, ".", fsys, opts, w)
+}
+
+// repairTree repairs fsys whose root is the code path dir and writes the changes with w,
+// back to fsys when w is nil.
+func repairTree(ctx context.Context, dir string, fsys fs.FS, opts Options, w Writer) error {
+	unlock, err := lockLibrary(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := configure(opts); err != nil {
+		return err
+	}
+	codePath, outputWriter, pending = dir, w, nil
+	defer func() { codePath, outputWriter, pending = "", nil, nil }()
+	if err := mapDirectory(ctx, fsys, ".", instrumentDir); err != nil {
+		return err
+	}
+	return applyChanges()
+}
+
+// writableFS is a read-only file system whose changes are written with a Writer.
+type writableFS struct {
+	fs.FS
+	Writer
+}
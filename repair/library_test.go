@@ -0,0 +1,90 @@
+package repair
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// memWriter keeps the files written in memory, by their path.
+type memWriter map[string]string
+
+func (m memWriter) Write(path string, content []byte, mode fs.FileMode) error {
+	m[path] = string(content)
+	return nil
+}
+
+func TestDirectoryWriter(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.go":     "package a\n\nfunc Open() {}\n",
+		"b.go":     "package a\n\n// Close closes.\nfunc Close() {}\n",
+		"sub/c.go": "package sub\n\ntype Store struct{}\n",
+	})
+	before := readTree(t, dir)
+	w := memWriter{}
+	if err := Directory(context.Background(), dir, Options{}, w); err != nil {
+		t.Fatal(err)
+	}
+	want := memWriter{
+		"a.go":     "package a\n\n// Open missing godoc.\nfunc Open() {}\n",
+		"sub/c.go": "package sub\n\n// Store missing godoc.\ntype Store struct{}\n",
+	}
+	if !reflect.DeepEqual(w, want) {
+		t.Errorf("written %q, want %q", w, want)
+	}
+	if after := readTree(t, dir); !reflect.DeepEqual(after, before) {
+		t.Error("the tree was changed in place")
+	}
+}
+
+func TestDirectoryCanceled(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": "package a\n\nfunc Open() {}\n"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := memWriter{}
+	if err := Directory(ctx, dir, Options{}, w); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	if len(w) != 0 {
+		t.Errorf("canceled run wrote %q", w)
+	}
+}
+
+func TestFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":      {Data: []byte("module fixture\n\ngo 1.18\n")},
+		"a.go":        {Data: []byte("package a\n\nfunc Open() {}\n")},
+		"sub/b.go":    {Data: []byte("package sub\n\n// Close closes.\nfunc Close() {}\n")},
+		"vendor/v.go": {Data: []byte("package v\n\nfunc V() {}\n")},
+	}
+	w := memWriter{}
+	if err := FS(context.Background(), fsys, Options{}, w); err != nil {
+		t.Fatal(err)
+	}
+	want := memWriter{"a.go": "package a\n\n// Open missing godoc.\nfunc Open() {}\n"}
+	if !reflect.DeepEqual(w, want) {
+		t.Errorf("written %q, want %q", w, want)
+	}
+}
+
+func TestFSReadOnly(t *testing.T) {
+	fsys := fstest.MapFS{"a.go": {Data: []byte("package a\n\nfunc Open() {}\n")}}
+	err := FS(context.Background(), fsys, Options{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("got error %v, want the file system to be read-only", err)
+	}
+}
+
+func TestFSWritable(t *testing.T) {
+	fsys := failingFS{MapFS: fstest.MapFS{"a.go": {Data: []byte("package a\n\nfunc Open() {}\n"), Mode: 0644}}}
+	if err := FS(context.Background(), fsys, Options{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(fsys.MapFS["a.go"].Data); got != "package a\n\n// Open missing godoc.\nfunc Open() {}\n" {
+		t.Errorf("a.go = %q, want it repaired in place", got)
+	}
+}
@@ -0,0 +1,171 @@
+package repair
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// lockFileName is the name of the lock file created at the root of the code path by the runs changing files.
+const lockFileName = ".godoc-repair.lock"
+
+// lockPollInterval is how often a lock held by another run is tried again with --lock-wait.
+const lockPollInterval = 100 * time.Millisecond
+
+// lockGrace is how long a lock file without a pid is taken for one being written by its holder.
+const lockGrace = time.Second
+
+// heldLock releases the lock held by the run, nil when it holds none. lockMu guards it, as the lock
+// is also released when a second signal exits.
+var (
+	lockMu   sync.Mutex
+	heldLock func()
+)
+
+// acquireLock creates the lock file of dir holding the pid of the process, waiting up to wait while another run
+// holds it, and makes it the lock released by releaseLock. The lock file of a process which is gone is taken over.
+func acquireLock(ctx context.Context, dir string, wait time.Duration) error {
+	fileName := filepath.Join(dir, lockFileName)
+	deadline := time.Now().Add(wait)
+	for {
+		f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(fileName)
+				return fmt.Errorf("failed writing lock file %s: %v", fileName, err)
+			}
+			lockMu.Lock()
+			heldLock = func() { removeOwnLock(fileName) }
+			lockMu.Unlock()
+			return nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("failed creating lock file %s: %v", fileName, err)
+		}
+		pid, held := lockHolder(fileName)
+		if !held {
+			if err := takeOverLock(fileName, pid); err != nil {
+				return err
+			}
+			continue
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%s is locked by process %d changing it, wait for it to finish or use --lock-wait or --no-lock", dir, pid)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// takeOverLock removes the stale lock file fileName of process pid, so it can be created again with O_EXCL.
+// Runs taking it over at once are serialized by a guard file created with O_EXCL, its holder checks the lock is
+// still the stale one before removing it: a lock file only changes when it is removed, by the live process
+// holding it or under the guard, so a fresh lock created meanwhile is never removed. A guard left behind by
+// a run which died taking a lock over is removed once older than lockGrace.
+func takeOverLock(fileName string, pid int) error {
+	guardName := fileName + ".takeover"
+	guard, err := os.OpenFile(guardName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		if info, err := os.Stat(guardName); err == nil && time.Since(info.ModTime()) > lockGrace {
+			os.Remove(guardName)
+		}
+		// another run is taking it over
+		time.Sleep(lockPollInterval / 10)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed taking over stale lock file %s: %v", fileName, err)
+	}
+	guard.Close()
+	defer os.Remove(guardName)
+	if current, held := lockHolder(fileName); held || current != pid {
+		// another run took it over first
+		return nil
+	}
+	if pid != 0 {
+		log.Printf("Removing stale lock file %s of process %d", fileName, pid)
+	} else {
+		log.Printf("Removing stale lock file %s", fileName)
+	}
+	if err := os.Remove(fileName); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed removing stale lock file %s: %v", fileName, err)
+	}
+	return nil
+}
+
+// removeOwnLock removes the lock file fileName if it still holds the pid of the process.
+func removeOwnLock(fileName string) {
+	if pid, _ := lockHolder(fileName); pid == os.Getpid() {
+		os.Remove(fileName)
+	}
+}
+
+// releaseLock releases the lock held by the run, if any.
+func releaseLock() {
+	lockMu.Lock()
+	defer lockMu.Unlock()
+	if heldLock != nil {
+		heldLock()
+		heldLock = nil
+	}
+}
+
+// exit releases the lock held by the run and exits with code, deferred calls do not run on os.Exit.
+func exit(code int) {
+	releaseLock()
+	os.Exit(code)
+}
+
+// fatal logs v and exits with 1 as log.Fatal does, releasing the lock held by the run.
+func fatal(v ...interface{}) {
+	log.Print(v...)
+	exit(1)
+}
+
+// fatalf logs as log.Printf and exits with 1 as log.Fatalf does, releasing the lock held by the run.
+func fatalf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+	exit(1)
+}
+
+// lockHolder returns the pid recorded in the lock file fileName and whether that process still holds the lock.
+// A lock file removed meanwhile is not held.
+func lockHolder(fileName string) (int, bool) {
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false
+	}
+	pid, perr := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || perr != nil {
+		// the holder may not have written its pid yet
+		info, err := os.Stat(fileName)
+		return 0, err == nil && time.Since(info.ModTime()) < lockGrace
+	}
+	return pid, processAlive(pid)
+}
+
+// processAlive reports whether the process pid is running. Signal 0 only checks for its existence on Unix,
+// on Windows finding the process fails once it is gone.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || !errors.Is(err, os.ErrProcessDone)
+}
@@ -0,0 +1,135 @@
+package repair
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lockFiles are sources with several declarations to describe, so a run holds the lock for a while.
+var lockFiles = map[string]string{
+	"a.go": "package lock\n\nfunc Open() {}\n\nfunc Close() {}\n\nfunc Read() {}\n",
+}
+
+// slowDescribe writes a --describe-cmd script to a temporary directory, returned with the script. The script
+// creates started in the directory, and appends to overlap when another run is describing at the same time.
+func slowDescribe(t *testing.T) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the describe command is a shell script")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "describe.sh")
+	src := fmt.Sprintf(`cat >/dev/null
+touch %[1]s/started
+mkdir %[1]s/running || echo overlap >>%[1]s/overlap
+sleep 0.2
+rmdir %[1]s/running
+echo "does x."
+`, dir)
+	if err := os.WriteFile(script, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, "sh " + script
+}
+
+// startRun starts the command with args in dir, returning its stderr filled once it was waited for.
+func startRun(t *testing.T, dir string, args ...string) (*exec.Cmd, *bytes.Buffer) {
+	t.Helper()
+	var stderr bytes.Buffer
+	cmd := command(dir, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd, &stderr
+}
+
+// checkNoOverlap fails t when two runs described declarations at the same time or the lock file was left.
+func checkNoOverlap(t *testing.T, dir, scriptDir string) {
+	t.Helper()
+	if _, err := os.Stat(filepath.Join(scriptDir, "overlap")); err == nil {
+		t.Error("two runs changed the tree at the same time")
+	}
+	if _, err := os.Stat(filepath.Join(dir, lockFileName)); err == nil {
+		t.Errorf("%s was left", lockFileName)
+	}
+}
+
+func TestLockConcurrentRuns(t *testing.T) {
+	scriptDir, describe := slowDescribe(t)
+	dir := writeTree(t, lockFiles)
+	first, firstStderr := startRun(t, dir, "-auto-description", "-describe-cmd", describe)
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		if _, err := os.Stat(filepath.Join(scriptDir, "started")); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the first run did not start describing")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, stderr, code := run(t, dir, "-auto-description", "-describe-cmd", describe)
+	if want := fmt.Sprintf("is locked by process %d", first.Process.Pid); code == 0 || !strings.Contains(stderr, want) {
+		t.Errorf("exit status %d, stderr %q, want %q", code, stderr, want)
+	}
+	second, secondStderr := startRun(t, dir, "-auto-description", "-describe-cmd", describe, "-lock-wait", "1m")
+	if err := first.Wait(); err != nil {
+		t.Fatalf("first run: %v: %s", err, firstStderr)
+	}
+	if err := second.Wait(); err != nil {
+		t.Fatalf("waiting run: %v: %s", err, secondStderr)
+	}
+	checkNoOverlap(t, dir, scriptDir)
+	if got := readTree(t, dir)["a.go"]; !strings.Contains(got, "// Open does x.") {
+		t.Errorf("file was not repaired:\n%s", got)
+	}
+}
+
+func TestLockStaleTakeover(t *testing.T) {
+	scriptDir, describe := slowDescribe(t)
+	dir := writeTree(t, lockFiles)
+	// the pid of a process which is gone
+	gone := exec.Command("sh", "-c", "exit 0")
+	if err := gone.Run(); err != nil {
+		t.Fatal(err)
+	}
+	pid := strconv.Itoa(gone.Process.Pid) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, lockFileName), []byte(pid), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var cmds []*exec.Cmd
+	var stderrs []*bytes.Buffer
+	for i := 0; i < 4; i++ {
+		cmd, stderr := startRun(t, dir, "-auto-description", "-describe-cmd", describe, "-lock-wait", "1m")
+		cmds, stderrs = append(cmds, cmd), append(stderrs, stderr)
+	}
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("run %d: %v: %s", i, err, stderrs[i])
+		}
+	}
+	checkNoOverlap(t, dir, scriptDir)
+	if strings.Contains(readTree(t, dir)["a.go"], "missing godoc") {
+		t.Error("file was not repaired")
+	}
+}
+
+func TestLockReleasedOnError(t *testing.T) {
+	dir := writeTree(t, lockFiles)
+	// undo takes the lock and then fails, there is no run to undo
+	_, stderr, code := run(t, dir, "-undo")
+	if code == 0 || !strings.Contains(stderr, "no run over") {
+		t.Fatalf("exit status %d, stderr %q, want no run to undo", code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, lockFileName)); err == nil {
+		t.Errorf("%s was left by the failing run", lockFileName)
+	}
+}
@@ -0,0 +1,1599 @@
+package repair
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+)
+
+const (
+	defaultCommentFormat  = "// %s missing godoc."
+	autoDescriptionFormat = "// %s %s"
+	defaultOptionsClause  = "additional behavior can be configured with %s"
+	defaultVariadicFormat = "accepts a variable number of %s values"
+	defaultContextFormat  = "The provided %s is used for cancellation and deadlines."
+)
+
+const (
+	kindType    = "type"
+	kindFunc    = "func"
+	kindMethod  = "method"
+	kindConst   = "const"
+	kindVar     = "var"
+	kindPackage = "package"
+	kindField   = "field"
+)
+
+// exitTimeout is the exit code of a run aborted by --timeout.
+const exitTimeout = 3
+
+// Policies for comments holding just the name, e.g. "// GetUser".
+const (
+	policyKeep     = "keep"
+	policyExpand   = "expand"
+	policyDescribe = "describe"
+)
+
+var (
+	commentFormat         string
+	codePath              string
+	autoDescription       bool
+	articles              bool
+	packageComment        bool
+	packageOnly           bool
+	optionsClause         string
+	variadicFormat        string
+	wrapWidth             int
+	mentionContext        bool
+	contextFormat         string
+	skipLineDirs          bool
+	includeTests          bool
+	examples              bool
+	check                 bool
+	allowlistFile         string
+	validateFormat        string
+	docParagraphs         bool
+	skipCtors             bool
+	todoOwner             string
+	lang                  string
+	wordSplit             bool
+	cacheDir              string
+	extraVerbs            string
+	phrasesFile           string
+	justNamePolicy        string
+	stamp                 bool
+	docLinks              bool
+	timeout               time.Duration
+	noLock                bool
+	lockWait              time.Duration
+	glossaryFile          string
+	implementsDocs        bool
+	copyInterfaceDocs     bool
+	formatMap             formatRules
+	funcFormat            string
+	methodFormat          string
+	deprecateFile         string
+	docBelowDirectives    bool
+	treatPlaceholders     bool
+	lineDirectives        bool
+	mentionResults        bool
+	diffBranch            string
+	offsetArg             string
+	output                string
+	onlySymbols           stringList
+	maxDepth              int
+	verbose               bool
+	kindsArg              string
+	excludeModules        stringList
+	maxChanges            int
+	force                 bool
+	reportOut             string
+	manifestFile          string
+	exportedReceiversOnly bool
+	planFile              string
+	verifyNameMatch       bool
+	patchOut              string
+	applyFile             string
+	undoRun               undoFlag
+	compareRange          string
+	documentFields        bool
+	useTags               bool
+	maxFileBytes          int64
+	strictArg             string
+	version               bool
+	enableArg             string
+	disableArg            string
+	enableAll             bool
+	severityArg           string
+	warningsAsErrors      bool
+	describeCmd           string
+	describeTimeout       time.Duration
+	baselineFile          string
+	writeBaseline         bool
+	pruneBaseline         bool
+	printConfigFlag       bool
+	internalFormat        string
+	commentStyle          string
+	countOnly             bool
+	countByArg            string
+	quietSuccess          bool
+	maxChangesMode        string
+	failFast              bool
+	outDir                string
+	requireTypeOverview   bool
+	forceStaleWrite       bool
+	chmodWritable         bool
+	normalizeNames        bool
+	nameSeparatorsArg     string
+	collapseBlankLines    bool
+	nameSeparators        []string
+)
+
+// flags are the command line flags, defined by init so their variables hold the defaults
+// also when the package is used as a library.
+var flags = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+func init() {
+	flags.StringVar(&commentFormat, "format", defaultCommentFormat, "comment format")
+	flags.StringVar(&codePath, "code-path", "", "code path")
+	flags.BoolVar(&autoDescription, "auto-description", false, "enable auto description")
+	flags.BoolVar(&articles, "articles", false, "insert articles into auto description of types, requires auto-description")
+	flags.BoolVar(&packageComment, "package-comment", false, "add a package comment to packages missing one")
+	flags.BoolVar(&packageOnly, "package-only", false, "only add package comments, skip all declarations")
+	flags.StringVar(&optionsClause, "options-clause", defaultOptionsClause, "auto description clause for a trailing functional options parameter")
+	flags.StringVar(&variadicFormat, "variadic-clause", defaultVariadicFormat, "auto description clause for a trailing variadic parameter")
+	flags.BoolVar(&mentionContext, "mention-context", false, "mention a leading context.Context parameter in auto description")
+	flags.StringVar(&contextFormat, "context-sentence", defaultContextFormat, "auto description sentence for a leading context.Context parameter")
+	flags.BoolVar(&skipLineDirs, "skip-line-directives", false, "skip files containing //line directives")
+	flags.BoolVar(&includeTests, "include-tests", false, "include _test.go files, test functions recognized by go test are skipped")
+	flags.BoolVar(&examples, "examples", false, "add Example function stubs to example_test.go for exported API without examples")
+	flags.BoolVar(&check, "check", false, "report exported declarations whose godoc needs repair without changing files, exit 1 if any")
+	flags.StringVar(&allowlistFile, "allow-undocumented", "", "file listing names or pkg.Name patterns which may remain undocumented in check mode")
+	flags.StringVar(&validateFormat, "validate-format", "", "validate the given comment format, print OK or the error and exit")
+	flags.BoolVar(&docParagraphs, "doc-paragraphs", false, "put the details of the auto description in a paragraph after the summary line")
+	flags.BoolVar(&skipCtors, "skip-constructors", false, "skip NewX functions returning X or *X")
+	flags.StringVar(&todoOwner, "todo-owner", "", "use TODO(owner) placeholders instead of the default comment format")
+	flags.StringVar(&lang, "lang", "en", "language of generated comments, a built-in catalog (en, ja) or a JSON catalog file")
+	flags.BoolVar(&wordSplit, "word-split", true, "describe declarations with the words of their name in auto description")
+	flags.StringVar(&cacheDir, "cache-dir", "", "directory of a cache of fully documented files to skip in later runs")
+	flags.StringVar(&extraVerbs, "verbs", "", "comma separated verbs conjugated in auto description in addition to the built-in ones")
+	flags.StringVar(&phrasesFile, "phrases", "", "JSON file mapping the first word of function names to phrase templates with {rest}")
+	flags.StringVar(&justNamePolicy, "justname-policy", policyExpand, "handling of comments holding just the name: keep, expand to the comment format or describe with auto description")
+	flags.BoolVar(&stamp, "stamp", false, "add a comment after the package clause of modified files noting they were documented by godoc-repair")
+	flags.BoolVar(&docLinks, "doc-links", false, "write exported names of the package and imported packages in auto description as doc links")
+	flags.DurationVar(&timeout, "timeout", 0, "abort the run when it takes longer, e.g. 30s, 0 disables the limit")
+	flags.BoolVar(&noLock, "no-lock", false, "change files without locking the code path against other runs")
+	flags.DurationVar(&lockWait, "lock-wait", 0, "how long to wait for another run changing the code path to finish, 0 fails at once")
+	flags.StringVar(&glossaryFile, "glossary", "", "YAML file mapping names, Type.Method, pkg.Name or patterns such as *.Close to descriptions")
+	flags.BoolVar(&implementsDocs, "implements", false, "document methods implementing a documented interface method of the module as implementing it, the closest package first")
+	flags.BoolVar(&copyInterfaceDocs, "copy-interface-docs", false, "with -implements copy the comment of the interface method instead")
+	flags.StringVar(&funcFormat, "func-format", "", "comment format of functions, taking precedence over the format of their file")
+	flags.StringVar(&methodFormat, "method-format", "", "comment format of methods, taking precedence over the format of their file")
+	flags.Var(&formatMap, "format-map", "glob=format applying the comment format to the matching files instead of -format, repeatable, the first match wins")
+	flags.StringVar(&deprecateFile, "deprecate", "", "YAML file mapping pkg.Symbol to a notice, add Deprecated paragraphs to these symbols instead of repairing docs")
+	flags.BoolVar(&docBelowDirectives, "doc-below-directives", false, "insert missing docs below directives such as //go:generate directly above declarations instead of above them")
+	flags.BoolVar(&treatPlaceholders, "treat-placeholders-as-missing", false, "in check mode report docs which are placeholders left by this tool as placeholder-doc findings")
+	flags.BoolVar(&lineDirectives, "line-directives", false, "report positions adjusted by //line directives instead of physical positions")
+	flags.BoolVar(&mentionResults, "mention-results", false, "mention named results of functions in auto description, e.g. returns n and err")
+	flags.StringVar(&diffBranch, "diff-branch", "", "only process the .go files changed since HEAD forked from the given branch, e.g. main, uncommitted changes included")
+	flags.StringVar(&offsetArg, "offset", "", "repair only the declaration enclosing the byte offset given as file.go:#1234 and print the file")
+	flags.StringVar(&output, "output", "file", "output of -offset: file prints the updated file, edits prints LSP text edits as JSON; output of -check: codeclimate prints a Code Climate report, junit JUnit XML, csv a row for each finding")
+	flags.Var(&onlySymbols, "symbol", "only process the declaration Name, Type.Method, pkg.Name or path/pkg.Name, repeatable")
+	flags.IntVar(&maxDepth, "max-depth", -1, "only descend this many directories below the code path, 0 processes the code path only, -1 is unlimited")
+	flags.BoolVar(&verbose, "v", false, "log what is skipped and why")
+	flags.StringVar(&kindsArg, "only-kinds", "", "comma separated kinds to process: type, func, method, const, var, package")
+	flags.Var(&includeModules, "module", "only process the module with the given module path or directory glob, repeatable")
+	flags.Var(&excludeModules, "exclude-module", "module path or directory glob of a module to leave out, repeatable")
+	flags.IntVar(&maxChanges, "max-changes", 0, "abort without writing anything when more files would be changed, 0 is unlimited")
+	flags.StringVar(&maxChangesMode, "max-changes-mode", changesAbort, "when there are more than -max-changes: abort changes nothing, truncate changes the first ones")
+	flags.BoolVar(&force, "force", false, "change the files even when there are more than -max-changes")
+	flags.StringVar(&reportOut, "report-out", "", "write the JSON report of the findings and changed files to this file")
+	flags.StringVar(&manifestFile, "manifest", "", "write the JSON list of the docs inserted or rewritten by the run to this file")
+	flags.BoolVar(&exportedReceiversOnly, "methods-exported-receivers-only", false, "skip methods of unexported types")
+	flags.StringVar(&planFile, "plan", "", "write the changes of the run to this plan file instead of changing any source")
+	flags.StringVar(&patchOut, "patch-out", "", "write the changes of the run to this file as a patch for git apply instead of changing any source")
+	flags.StringVar(&applyFile, "apply", "", "apply the changes of a plan file written by -plan, failing if any file changed since")
+	flags.Var(&undoRun, "undo", "revert the files changed by the last run, or by the run given as -undo=<run-id>")
+	flags.StringVar(&compareRange, "compare", "", "report exported declarations undocumented in head but not in base, given as base..head git revisions")
+	flags.BoolVar(&documentFields, "fields", false, "also document the exported fields of exported struct types")
+	flags.BoolVar(&useTags, "use-tags", false, "with -fields and -auto-description mention the names given to fields by their tags, e.g. (yaml: port)")
+	flags.Int64Var(&maxFileBytes, "max-file-bytes", 0, "skip files larger than this many bytes, 0 is unlimited")
+	flags.StringVar(&strictArg, "strict", "", "comma separated strict rules to enforce on existing docs: start-with-name, ends-with-period, non-trivial or all, same as -enable")
+	flags.StringVar(&enableArg, "enable", "", "comma separated rules to enable in addition to the default ones")
+	flags.StringVar(&disableArg, "disable", "", "comma separated rules to disable, their findings and fixes are left out")
+	flags.BoolVar(&enableAll, "enable-all", false, "enable every rule, -disable still applies")
+	flags.BoolVar(&version, "version", false, "print the versions of the binary and of the modules it was built with and exit")
+	flags.StringVar(&severityArg, "severity", "", "comma separated rule=severity pairs, error (default) or warning, only errors make -check fail")
+	flags.BoolVar(&warningsAsErrors, "warnings-as-errors", false, "make -check fail on warnings too")
+	flags.StringVar(&describeCmd, "describe-cmd", "", "command printing the description of a declaration given as JSON on stdin, used with -auto-description")
+	flags.DurationVar(&describeTimeout, "describe-timeout", 10*time.Second, "time -describe-cmd is given for each declaration")
+	flags.StringVar(&baselineFile, "baseline", "", "JSON file of findings -check does not report, written with -write-baseline")
+	flags.BoolVar(&writeBaseline, "write-baseline", false, "with -check record the current findings in the -baseline file and exit")
+	flags.BoolVar(&pruneBaseline, "prune-baseline", false, "with -check remove the stale entries of the -baseline file")
+	flags.StringVar(&internalFormat, "internal-format", "", "comment format of the files under an internal directory, -format-map takes precedence")
+	flags.StringVar(&commentStyle, "comment-style", "", "style enforced on existing docs: godoc (start with the name and end with a period) or sentence (end with a period)")
+	flags.BoolVar(&countOnly, "count", false, "check and print only the number of findings")
+	flags.BoolVar(&verifyNameMatch, "verify-name-match", false, "check and report the docs starting with another name than their declaration's, e.g. after a rename, same as -check -enable name-mismatch")
+	flags.StringVar(&countByArg, "count-by", "", "check and print the number of findings by kind, package or rule as name<TAB>count lines")
+	flags.BoolVar(&quietSuccess, "quiet-success", false, "leave out progress logs, so -check prints nothing unless there are findings")
+	flags.BoolVar(&failFast, "fail-fast", false, "with -check stop at the first finding making the run fail")
+	flags.StringVar(&outDir, "out-dir", "", "write the changed files to this directory mirroring the code path instead of changing them in place")
+	flags.BoolVar(&requireTypeOverview, "require-type-overview", false, "with -check also list the documented types with undocumented methods, grouped by type")
+	flags.BoolVar(&normalizeNames, "normalize-first-line-name", false, "rewrite docs starting with the name followed by a separator of -name-separators, e.g. // Name - does x, as // Name does x")
+	flags.BoolVar(&collapseBlankLines, "collapse-multiple-blank-comment-lines", false, "collapse consecutive blank // lines of the docs of the declarations of repaired files to one")
+	flags.StringVar(&nameSeparatorsArg, "name-separators", ": - — – ()", "space separated separators following the name removed by -normalize-first-line-name")
+	flags.BoolVar(&forceStaleWrite, "force-stale-write", false, "write files even when they were modified since the run read them, discarding these modifications")
+	flags.BoolVar(&chmodWritable, "chmod-writable", false, "change read-only files by making them writable while they are written, they are left unchanged otherwise")
+	flags.BoolVar(&printConfigFlag, "print-config", false, "print the effective configuration as JSON and exit")
+	flags.IntVar(&wrapWidth, "wrap", 0, "wrap generated comments at the given width, 0 disables wrapping")
+}
+
+// Main runs the command with the arguments of os.Args.
+func Main() {
+	// the flag set exits with status 2 on errors as the default command line does
+	_ = flags.Parse(os.Args[1:])
+	// the tree is given by --code-path or go.work, a stray argument would be ignored silently
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.Output(), "unexpected arguments %s, give the directory to repair with -code-path\n", strings.Join(flags.Args(), " "))
+		flags.Usage()
+		exit(2)
+	}
+	if version {
+		printVersion(os.Stdout)
+		return
+	}
+	if validateFormat != "" {
+		if _, err := parseFormat(validateFormat); err != nil {
+			fmt.Println(err)
+			exit(1)
+		}
+		fmt.Println("OK")
+		return
+	}
+	addVerbs(extraVerbs)
+	if phrasesFile != "" {
+		if err := loadPhrases(phrasesFile); err != nil {
+			fatal(err)
+		}
+	}
+	m, err := loadCatalog(lang)
+	if err != nil {
+		fatal(err)
+	}
+	catalog = m
+	if countByArg != "" {
+		if _, ok := countBy[countByArg]; !ok {
+			fatalf("invalid count grouping %q, expected kind, package or rule", countByArg)
+		}
+		countOnly = true
+	}
+	// counting is checking with a different output
+	if countOnly || verifyNameMatch {
+		check = true
+	}
+	formatGiven := flagSet("format")
+	given := givenFlags()
+	// phrases not given on the command line come from the catalog
+	for name, key := range catalogFlags {
+		if !flagSet(name) {
+			if err := flags.Set(name, catalog[key]); err != nil {
+				fatal(err)
+			}
+		}
+	}
+	if todoOwner != "" && !formatGiven {
+		format, err := todoFormat(todoOwner)
+		if err != nil {
+			fatal(err)
+		}
+		commentFormat = format
+	}
+	switch justNamePolicy {
+	case policyKeep, policyExpand, policyDescribe:
+	default:
+		fatalf("invalid justname policy %q, expected keep, expand or describe", justNamePolicy)
+	}
+	for _, clause := range []string{optionsClause, variadicFormat, contextFormat} {
+		if err := validatePrintf(clause, 1); err != nil {
+			fatalf("invalid auto description phrase: %v", err)
+		}
+	}
+	tmpl, err := parseFormat(commentFormat)
+	if err != nil {
+		fatalf("invalid comment format: %v", err)
+	}
+	commentTemplate = tmpl
+	if internalFormat != "" {
+		tmpl, err := parseFormat(internalFormat)
+		if err != nil {
+			fatalf("invalid internal comment format: %v", err)
+		}
+		internalRule = formatRule{format: internalFormat, tmpl: tmpl}
+	}
+	for kind, format := range map[string]string{kindFunc: funcFormat, kindMethod: methodFormat} {
+		if format == "" {
+			continue
+		}
+		tmpl, err := parseFormat(format)
+		if err != nil {
+			fatalf("invalid %s comment format: %v", kind, err)
+		}
+		kindFormats[kind] = formatRule{format: format, tmpl: tmpl}
+	}
+
+	// get the current working directory if code path is empty
+	if codePath == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			fatalf("error getting current working directory: %v", err)
+		}
+		codePath = wd
+	}
+	// without a code path the modules of a go.work file in the working directory are walked
+	roots := []string{"."}
+	if !flagSet("code-path") {
+		modules, err := workspaceModules(codePath, excludeModules)
+		if err != nil {
+			fatal(err)
+		}
+		if modules != nil {
+			workspace = true
+			roots = modules
+			if kept, dropped := dedupeRoots(codePath, modules); len(dropped) > 0 {
+				log.Printf("Skipping the modules listed again in go.work: %s", strings.Join(dropped, ", "))
+				roots = kept
+			}
+			logProgress("Walking the modules of go.work: %s", strings.Join(roots, ", "))
+		}
+	}
+	if glossaryFile != "" {
+		m, err := loadGlossary(glossaryFile)
+		if err != nil {
+			fatal(err)
+		}
+		glossary = m
+	}
+	// --strict, --treat-placeholders-as-missing and --comment-style predate --enable and enable their rules
+	enable := []string{}
+	if enableArg != "" {
+		enable = append(enable, enableArg)
+	}
+	if strictArg == "all" {
+		enable = append(enable, ruleStartWithName, ruleEndsWithPeriod, ruleNonTrivial)
+	} else if strictArg != "" {
+		enable = append(enable, strictArg)
+	}
+	if treatPlaceholders {
+		enable = append(enable, rulePlaceholderDoc)
+	}
+	if verifyNameMatch {
+		enable = append(enable, ruleNameMismatch)
+	}
+	switch commentStyle {
+	case "":
+	case styleGodoc:
+		enable = append(enable, ruleStartWithName, ruleEndsWithPeriod)
+	case styleSentence:
+		enable = append(enable, ruleEndsWithPeriod)
+	default:
+		fatalf("invalid comment style %q, expected godoc or sentence", commentStyle)
+	}
+	if err := configureRules(strings.Join(enable, ","), disableArg, enableAll); err != nil {
+		fatal(err)
+	}
+	if severityArg != "" {
+		m, err := parseSeverities(severityArg)
+		if err != nil {
+			fatal(err)
+		}
+		severities = m
+	}
+	if failFast && check && baselineFile != "" && !writeBaseline {
+		b, err := loadBaseline(baselineFile)
+		if err != nil {
+			fatal(err)
+		}
+		grandfathered = make(map[baselineEntry]bool)
+		for _, e := range b.Findings {
+			grandfathered[e] = true
+		}
+	}
+	nameSeparators = strings.Fields(nameSeparatorsArg)
+	if outDir != "" {
+		outputWriter = shadowDir{dir: outDir}
+	}
+	if maxChangesMode != changesAbort && maxChangesMode != changesTruncate {
+		fatalf("invalid max changes mode %q, expected %s or %s", maxChangesMode, changesAbort, changesTruncate)
+	}
+	if kindsArg != "" {
+		kinds, err := parseKinds(kindsArg)
+		if err != nil {
+			fatal(err)
+		}
+		onlyKinds = kinds
+	}
+	if printConfigFlag {
+		if err := printConfig(os.Stdout, given); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if diffBranch != "" {
+		files, err := diffBranchFiles(codePath, diffBranch)
+		if err != nil {
+			fatal(err)
+		}
+		changedFiles = files
+	}
+	if deprecateFile != "" {
+		m, err := loadDeprecations(deprecateFile)
+		if err != nil {
+			fatal(err)
+		}
+		deprecations = m
+	}
+	if allowlistFile != "" {
+		patterns, err := loadAllowlist(allowlistFile)
+		if err != nil {
+			fatalf("error loading allowlist: %v", err)
+		}
+		allowlist = patterns
+	}
+	if (output == outputCodeClimate || output == outputJUnit || output == outputCSV) && (!check || offsetArg != "") {
+		fatalf("-output %s requires -check", output)
+	}
+	// the run is canceled after --timeout or when interrupted, work in progress is dropped
+	// as files are only written at the end
+	ctx := cancelOnSignal(context.Background())
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if offsetArg != "" {
+		if output != "file" && output != "edits" {
+			fatalf("invalid output %q, expected file or edits", output)
+		}
+		if err := runOffset(ctx, offsetArg, output, os.Stdout); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if compareRange != "" {
+		offenders, err := compareRefs(codePath, compareRange)
+		if err != nil {
+			fatal(err)
+		}
+		for _, f := range offenders {
+			fmt.Println(f)
+		}
+		if failing(offenders) {
+			exit(1)
+		}
+		return
+	}
+	// runs changing the tree in place exclude each other, their writes would interleave
+	if !noLock && (undoRun != "" || applyFile != "" || !check && planFile == "" && patchOut == "" && outDir == "") {
+		if err := acquireLock(ctx, codePath, lockWait); err != nil {
+			fatal(err)
+		}
+		defer releaseLock()
+	}
+	if undoRun != "" {
+		if err := undo(string(undoRun)); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if applyFile != "" {
+		if err := loadPlan(newDirFS(codePath), applyFile); err != nil {
+			fatal(err)
+		}
+		if err := checkChanges(); err != nil {
+			fatal(err)
+		}
+		if err := applyChanges(); err != nil {
+			fatal(err)
+		}
+		log.Printf("Applied changes to %d files from %s", len(pending), applyFile)
+		return
+	}
+	if check {
+		logProgress("Checking go doc of each exported type/func recursively in %s", codePath)
+	} else {
+		logProgress("Adding default go doc to each exported type/func recursively in %s", codePath)
+	}
+
+	// a run restricted to some symbols or kinds says nothing about the rest of the files
+	if cacheDir != "" && !examples && len(onlySymbols) == 0 && onlyKinds == nil {
+		fileCache = loadCache(cacheDir)
+	}
+
+	operation := instrumentDir
+	if examples {
+		operation = exampleDir
+	}
+	for _, root := range roots {
+		if err := mapDirectory(ctx, newDirFS(codePath), root, operation); err != nil {
+			if errors.Is(err, errFailFast) {
+				stopAtFirstFailure()
+				break
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				log.Printf("aborted after the timeout of %s, no file was changed", timeout)
+				exit(exitTimeout)
+			}
+			if errors.Is(err, context.Canceled) {
+				log.Print("interrupted, no file was changed")
+				printSummary()
+				exit(exitInterrupted)
+			}
+			fatalf("error while instrumenting current working directory: %v", err)
+		}
+	}
+	if planFile != "" {
+		if err := writePlan(planFile); err != nil {
+			fatal(err)
+		}
+		log.Printf("Planned changes to %d files in %s", len(pending), planFile)
+	} else if patchOut != "" {
+		if err := writePatch(patchOut); err != nil {
+			fatal(err)
+		}
+		logProgress("Wrote changes to %d files to %s", len(pending), patchOut)
+	} else {
+		if err := checkChanges(); err != nil {
+			fatal(err)
+		}
+		if err := applyChanges(); err != nil {
+			fatal(err)
+		}
+		if manifestFile != "" {
+			if err := writeManifest(manifestFile); err != nil {
+				fatal(err)
+			}
+		}
+	}
+	if deprecations != nil && !check && !examples {
+		for _, symbol := range missingDeprecations() {
+			log.Printf("deprecated symbol %s was not found", symbol)
+		}
+	}
+	// files skipped by the cache are not looked up, entries for them would be reported wrongly
+	if !check && !examples && fileCache == nil {
+		for _, key := range unusedGlossary() {
+			log.Printf("glossary entry %q matched no declaration", key)
+		}
+	}
+	// nothing was written when planning, the files recorded as documented are not yet
+	if fileCache != nil && planFile == "" && patchOut == "" {
+		if err := fileCache.save(cacheDir); err != nil {
+			log.Printf("error saving cache: %v", err)
+		}
+	}
+	if check && baselineFile != "" {
+		filterBaseline()
+	}
+	if reportOut != "" {
+		if err := writeReport(reportOut); err != nil {
+			fatal(err)
+		}
+	}
+	if missing := missingSymbols(); len(missing) > 0 {
+		if countOnly {
+			printCount(os.Stdout, countByArg)
+		} else if check {
+			printFindings(os.Stdout)
+		}
+		for _, symbol := range missing {
+			log.Printf("symbol %s was not found", symbol)
+		}
+		exit(1)
+	}
+	if countOnly {
+		printCount(os.Stdout, countByArg)
+		return
+	}
+	if check {
+		var err error
+		switch output {
+		case outputCodeClimate:
+			err = printCodeClimate(os.Stdout)
+		case outputJUnit:
+			err = printJUnit(os.Stdout)
+		case outputCSV:
+			err = printCSV(os.Stdout)
+		default:
+			printFindings(os.Stdout)
+			if requireTypeOverview {
+				printOverview(os.Stdout)
+			}
+		}
+		if err != nil {
+			fatal(err)
+		}
+		if len(suppressed) > 0 {
+			logProgress("%d findings suppressed by //nolint", len(suppressed))
+		}
+	}
+	printSummary()
+	if check && failing(findings) {
+		exit(1)
+	}
+}
+
+// filterBaseline writes the baseline with --write-baseline and exits, otherwise drops the findings of the
+// baseline and reports its stale entries, which --prune-baseline removes from it.
+func filterBaseline() {
+	if writeBaseline {
+		entries := currentBaseline()
+		if err := saveBaseline(baselineFile, entries); err != nil {
+			fatal(err)
+		}
+		log.Printf("Recorded %d findings in baseline %s", len(entries), baselineFile)
+		exit(0)
+	}
+	b, err := loadBaseline(baselineFile)
+	if err != nil {
+		fatal(err)
+	}
+	stale := applyBaseline(b)
+	// a partial run does not see every finding, entries missing from it may still be valid
+	if len(onlySymbols) > 0 || onlyKinds != nil || changedFiles != nil || failFast {
+		return
+	}
+	for _, e := range stale {
+		log.Printf("stale baseline entry: %s %s.%s [%s] in %s", e.Kind, e.Package, e.Symbol, e.Rule, e.Dir)
+	}
+	if pruneBaseline && len(stale) > 0 {
+		kept := b.Findings[:0]
+		isStale := make(map[baselineEntry]bool)
+		for _, e := range stale {
+			isStale[e] = true
+		}
+		for _, e := range b.Findings {
+			if !isStale[e] {
+				kept = append(kept, e)
+			}
+		}
+		if err := saveBaseline(baselineFile, kept); err != nil {
+			fatal(err)
+		}
+		log.Printf("Removed %d stale entries from baseline %s", len(stale), baselineFile)
+	}
+}
+
+// catalogFlags maps the flags overriding a catalog message to the message key.
+var catalogFlags = map[string]string{
+	"format":           msgPlaceholder,
+	"options-clause":   msgOptions,
+	"variadic-clause":  msgVariadic,
+	"context-sentence": msgContext,
+}
+
+// logProgress logs the progress of the run unless --quiet-success is given.
+func logProgress(format string, v ...interface{}) {
+	if !quietSuccess {
+		log.Printf(format, v...)
+	}
+}
+
+// flagSet reports whether the flag name was given on the command line.
+func flagSet(name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func instrumentDir(ctx context.Context, fsys fs.FS, dir string) error {
+	fset := token.NewFileSet()
+	filter := func(entry fs.DirEntry) bool {
+		fileName := path.Join(dir, entry.Name())
+		return testsFilter(entry) && changedFilter(fileName) && sizeFilter(fileName, entry) &&
+			generatedFilter(fsys, dir, entry) && cachedFilter(fsys, fileName)
+	}
+	pkgs, err := parseDir(fset, fsys, dir, filter)
+	if err != nil {
+		return fmt.Errorf("failed parsing go files in directory %s: %v", dir, err)
+	}
+
+	// a directory may hold several packages, e.g. foo and its external tests in foo_test
+	names := make([]string, 0, len(pkgs))
+	for name := range pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pkg := pkgs[name]
+		if isExternalTestPkg(pkg) {
+			if !includeTests {
+				log.Printf("Skipping external test package %s in directory %s", name, dir)
+				continue
+			}
+			dropNonTestFiles(pkg)
+		}
+		if err := instrumentPkg(ctx, fset, fsys, pkg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isExternalTestPkg reports whether pkg is an external test package, e.g. foo_test.
+func isExternalTestPkg(pkg *ast.Package) bool {
+	return strings.HasSuffix(pkg.Name, "_test")
+}
+
+// dropNonTestFiles removes the files of the external test package pkg which are not _test.go files,
+// only _test.go files can belong to an external test package so the others are left alone.
+func dropNonTestFiles(pkg *ast.Package) {
+	for _, fileName := range sortedFiles(pkg) {
+		if !strings.HasSuffix(fileName, "_test.go") {
+			log.Printf("Skipping file %s of external test package %s", fileName, pkg.Name)
+			delete(pkg.Files, fileName)
+		}
+	}
+}
+
+func instrumentPkg(ctx context.Context, fset *token.FileSet, fsys fs.FS, pkg *ast.Package) (err error) {
+	wfs, ok := fsys.(writeFS)
+	if !ok && !check {
+		return fmt.Errorf("failed instrumenting package %s: file system is read-only", pkg.Name)
+	}
+	// a package is changed as a whole or not at all, the files staged before a failure are dropped
+	staged := len(pending)
+	defer func() {
+		if err != nil {
+			pending = pending[:staged]
+		}
+	}()
+	var docFile string
+	if (packageComment || packageOnly) && len(onlySymbols) == 0 && (onlyKinds == nil || onlyKinds[kindPackage]) && ruleEnabled(ruleMissingDoc) {
+		docFile = packageDocFile(pkg)
+	}
+	// the symbol table covers every file, so declarations can refer to those of other files
+	symbols = buildSymbols(pkg)
+	if implementsDocs && len(pkg.Files) > 0 {
+		collectImplementations(fsys, path.Dir(sortedFiles(pkg)[0]), symbols)
+	}
+	// files are processed in order so logs and writes are the same on every run
+	for _, fileName := range sortedFiles(pkg) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		file := pkg.Files[fileName]
+		if packageOnly && fileName != docFile {
+			continue
+		}
+		if skipLineDirs && hasLineDirective(file) {
+			log.Printf("Skipping file %s containing //line directives", fileName)
+			continue
+		}
+		if check {
+			before := len(findings)
+			restore := useFormat(fileName)
+			checkFile(fset, file, fileName == docFile)
+			if output == outputCSV && len(findings) > before {
+				if err := suggestComments(ctx, fset, file, fileName == docFile, findings[before:]); err != nil {
+					restore()
+					return fmt.Errorf("failed instrumenting file %s: %v", fileName, err)
+				}
+			}
+			restore()
+			recordChecked(fileName, pkg.Name)
+			if failFast && firstFailure(findings[before:]) >= 0 {
+				return errFailFast
+			}
+			if fileCache != nil {
+				if src, err := fs.ReadFile(fsys, fileName); err == nil {
+					fileCache.record(fileName, src, len(findings) == before)
+				}
+			}
+			continue
+		}
+		var buf bytes.Buffer
+		fileEdits = nil
+		restore := useFormat(fileName)
+		err := instrumentFile(ctx, fset, file, fileName == docFile, &buf)
+		restore()
+		if err != nil {
+			return fmt.Errorf("failed instrumenting file %s: %v", fileName, err)
+		}
+		info, err := fs.Stat(fsys, fileName)
+		if err != nil {
+			return fmt.Errorf("failed opening file %s: %v", fileName, err)
+		}
+		out := buf.Bytes()
+		src, err := fs.ReadFile(fsys, fileName)
+		if err != nil {
+			return fmt.Errorf("failed reading file %s: %v", fileName, err)
+		}
+		if !bytes.Equal(src, out) {
+			if stamp {
+				if out, err = stampFile(out); err != nil {
+					return fmt.Errorf("failed stamping file %s: %v", fileName, err)
+				}
+			}
+			var edits []edit
+			if manifestFile != "" {
+				if edits, err = resolveEdits(fileName, out, fileEdits); err != nil {
+					return fmt.Errorf("failed parsing repaired file %s: %v", fileName, err)
+				}
+			}
+			stage(wfs, fileName, pkg.Name, parsedHashes[fileName], out, info.Mode().Perm(), edits)
+		}
+		// the file holds the repaired content now, which is fully documented
+		fileCache.record(fileName, out, !packageOnly)
+	}
+	return nil
+}
+
+// packageDocFile returns the file of pkg which should hold the package comment,
+// or an empty string if the package is already documented.
+// A doc.go file is preferred, then a file named after the package, then the first file by name.
+func packageDocFile(pkg *ast.Package) string {
+	var names []string
+	for fileName, file := range pkg.Files {
+		if file.Doc != nil {
+			return ""
+		}
+		names = append(names, fileName)
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	for _, preferred := range []string{"doc.go", pkg.Name + ".go"} {
+		for _, name := range names {
+			if path.Base(name) == preferred {
+				return name
+			}
+		}
+	}
+	return names[0]
+}
+
+func instrumentFile(ctx context.Context, fset *token.FileSet, file *ast.File, pkgDoc bool, out io.Writer) error {
+	// Needed because ast does not support floating comments and deletes them.
+	// In order to preserve all comments we just pre-parse it to dst which treats them as first class citizens.
+	dec := decorator.NewDecorator(fset)
+	f, err := dec.DecorateFile(file)
+	if err != nil {
+		return fmt.Errorf("failed converting file from ast to dst: %v", err)
+	}
+	nolint := newNolintScope(fset, file)
+	newDecl := func(name, kind string, n dst.Node) declaration {
+		pos := dec.Ast.Nodes[n].Pos()
+		return declaration{name: name, kind: kind, pkg: f.Name.Name, pos: position(fset, pos), nolint: nolint.covers(fset, pos)}
+	}
+
+	if pkgDoc && !nolint.file {
+		doc := fmt.Sprintf(catalog[msgPackage], f.Name.Name)
+		f.Decs.Start.Append(doc)
+		recordEdit(declaration{name: f.Name.Name, kind: kindPackage}, actionInserted, []string{doc}, []string{ruleMissingDoc})
+	}
+	if packageOnly {
+		return decorator.Fprint(out, f)
+	}
+
+	testFile := strings.HasSuffix(fset.File(file.Pos()).Name(), "_test.go")
+	dst.Inspect(f, func(n dst.Node) bool {
+		switch t := n.(type) {
+		case *dst.FuncDecl:
+			if testFile && isTestingFunc(t.Name.Name, t.Recv != nil, fieldTypes(t.Type.Params), fieldTypes(t.Type.Results)) {
+				return true
+			}
+			d := newDecl(t.Name.Name, kindFunc, t)
+			d.fn = t.Type
+			d.results = fieldTypes(t.Type.Results)
+			if t.Recv != nil {
+				d.kind = kindMethod
+				d.recvType = receiverTypeName(t.Recv)
+				d.receiver = exprString(t.Recv.List[0].Type)
+			}
+			t.Decs.Start = autoDecl(ctx, d, t.Decs.Start)
+		case *dst.GenDecl:
+			kind := genDeclKind(t.Tok)
+			if len(t.Specs) == 1 {
+				switch s := t.Specs[0].(type) {
+				case *dst.TypeSpec:
+					d := newDecl(s.Name.Name, kind, s)
+					d.spec = s
+					t.Decs.Start = autoDecl(ctx, d, t.Decs.Start)
+					instrumentFields(ctx, s, newDecl)
+					return true
+				case *dst.ValueSpec:
+					t.Decs.Start = autoDecl(ctx, valueDecl(newDecl(specName(s.Names), kind, s), s), t.Decs.Start)
+					return true
+				default:
+					return true
+				}
+			}
+			for _, spec := range t.Specs {
+				switch s := spec.(type) {
+				case *dst.TypeSpec:
+					d := newDecl(s.Name.Name, kind, s)
+					d.spec = s
+					s.Decs.Start = autoDecl(ctx, d, s.Decs.Start)
+					instrumentFields(ctx, s, newDecl)
+				case *dst.ValueSpec:
+					s.Decs.Start = autoDecl(ctx, valueDecl(newDecl(specName(s.Names), kind, s), s), s.Decs.Start)
+				}
+			}
+		}
+		return true
+	})
+	return decorator.Fprint(out, f)
+}
+
+// specName returns the name a value spec is documented by, which has a single doc for all its names:
+// its first exported name, e.g. E for "_, E = iota, iota + 1", otherwise the first one.
+func specName(names []*dst.Ident) string {
+	for _, name := range names {
+		if token.IsExported(name.Name) {
+			return name.Name
+		}
+	}
+	return names[0].Name
+}
+
+// valueDecl completes d declared by the value spec s with the value of a var declaring a single name,
+// and whether it is of function type.
+func valueDecl(d declaration, s *dst.ValueSpec) declaration {
+	if d.kind != kindVar {
+		return d
+	}
+	if len(s.Names) == 1 && len(s.Values) == 1 {
+		d.value = s.Values[0]
+	}
+	_, funcType := s.Type.(*dst.FuncType)
+	_, funcLit := d.value.(*dst.FuncLit)
+	d.callback = funcType || funcLit
+	return d
+}
+
+// genDeclKind maps the token of a GenDecl to the kind of its declarations,
+// whether the GenDecl holds a single spec documented at its level or a group.
+func genDeclKind(tok token.Token) string {
+	switch tok {
+	case token.TYPE:
+		return kindType
+	case token.CONST:
+		return kindConst
+	default:
+		return kindVar
+	}
+}
+
+// skipDecl reports whether d is left alone, being unexported or excluded by the options.
+func skipDecl(d declaration) bool {
+	return !token.IsExported(d.name) || skipCtors && isConstructor(d) ||
+		exportedReceiversOnly && d.kind == kindMethod && !token.IsExported(d.recvType)
+}
+
+func autoDecl(ctx context.Context, d declaration, decorations dst.Decorations) dst.Decorations {
+	if targetOffsets != nil && !targetOffsets[d.pos.Offset] || !selected(d) {
+		return decorations
+	}
+	if deprecations != nil {
+		return deprecate(d, decorations)
+	}
+	if skipDecl(d) {
+		return decorations
+	}
+
+	lines := docLines(ctx, d, autoDescription)
+	// only the comment group directly above the declaration is its doc, comments separated
+	// by an empty line are kept as they are and the doc is inserted below them
+	all := decorations.All()
+	split := attachedStart(all)
+	detached, attached := all[:split:split], all[split:]
+	// directives such as //line must stay where they are, the doc is looked for after them
+	lead := leadingDirectives(attached)
+	if d.nolint || anySuppresses(attached) {
+		return decorations
+	}
+	// block comments are read as line comments, they are only rewritten when the doc is repaired,
+	// or when they are on the line of the declaration where go/doc does not take them as its doc
+	doc, inline := lineComments(attached[lead:])
+	state := Classify(doc, d.name)
+	switch state {
+	case JustName:
+		switch justNamePolicy {
+		case policyKeep:
+			state = OK
+		case policyDescribe:
+			lines = docLines(ctx, d, true)
+		}
+	case WrongPrefix:
+		if ruleEnabled(ruleStartWithName) && startsWithArticle(doc[0], d.name) {
+			state = OK
+		}
+	}
+	// each fix belongs to a rule, those of disabled rules are left out
+	fix := ruleEnabled(ruleMissingDoc)
+	var rules []string
+	// a placeholder doc is rewritten, but --check only reports it with the placeholder-doc rule
+	unreported := false
+	switch {
+	case state.missing() || state == Deprecated || inline || state == WrongPrefix && !ruleEnabled(ruleStartWithName) ||
+		state == JustName && !ruleEnabled(ruleNonTrivial):
+		if !fix {
+			state, inline = OK, false
+			break
+		}
+		rules = append(rules, ruleMissingDoc)
+	case state == WrongPrefix:
+		rules = append(rules, ruleStartWithName)
+	case state == JustName:
+		rules = append(rules, ruleNonTrivial)
+	case fix && len(doc) == 1 && isPlaceholder(doc[0], d):
+		rules = append(rules, ruleMissingDoc)
+		unreported = !ruleEnabled(rulePlaceholderDoc)
+	}
+	if state != JustName && ruleEnabled(ruleEndsWithPeriod) && !endsWithPunctuation(doc) {
+		rules = append(rules, ruleEndsWithPeriod)
+	}
+	countDecl(d, len(rules) > 1 || len(rules) == 1 && !unreported)
+	if len(rules) == 0 {
+		// block comments are left as they are, only line comments are collapsed
+		if collapseBlankLines && len(collapseBlanks(attached[lead:])) < len(attached[lead:]) {
+			attached = append(attached[:lead:lead], collapseBlanks(attached[lead:])...)
+			recordEdit(d, actionRewritten, attached[lead:], nil)
+			decorations.Replace(append(detached, attached...)...)
+		}
+		return decorations
+	}
+	attached = append(attached[:lead:lead], doc...)
+	switch {
+	case state.missing() && docBelowDirectives:
+		attached = append(append(attached[:lead:lead], lines...), attached[lead:]...)
+	case state.missing():
+		attached = append(lines, attached...)
+	case state == Deprecated:
+		// the Deprecated paragraph stays a paragraph of its own below the summary
+		attached = append(append(append(attached[:lead:lead], lines...), "//"), attached[lead:]...)
+	case state == WrongPrefix:
+		first := attached[lead]
+		first = trimPrefix(first, d.name)
+		first = fmt.Sprintf("// %s %s", d.name, first)
+		attached[lead] = first
+	}
+	// a lone placeholder written by an earlier run is upgraded to the current rendering
+	placeholder := fix && !state.missing() && len(attached) == lead+1 && isPlaceholder(attached[lead], d)
+	if state == JustName || placeholder {
+		attached = append(append(attached[:lead:lead], lines...), attached[lead+1:]...)
+	}
+	if ruleEnabled(ruleEndsWithPeriod) {
+		addPeriod(attached[lead:])
+	}
+	if collapseBlankLines {
+		attached = append(attached[:lead:lead], collapseBlanks(attached[lead:])...)
+	}
+	action := actionRewritten
+	if state.missing() {
+		action = actionInserted
+	}
+	recordEdit(d, action, attached[lead:], rules)
+	decorations.Replace(append(detached, attached...)...)
+	return decorations
+}
+
+// docLines returns the lines of the doc generated for d, the comment format or the auto description.
+func docLines(ctx context.Context, d declaration, description bool) []string {
+	doc := formatComment(d)
+	var paragraph string
+	if desc, ok := glossaryDescription(d); ok {
+		doc = fmt.Sprintf(autoDescriptionFormat, d.name, desc)
+	} else if lines, ok := implementationDoc(d); ok {
+		return lines
+	} else if description {
+		// the description of --describe-cmd takes the place of the built-in one, which is its fallback
+		if cmdDesc, ok := commandDescription(ctx, d); ok {
+			doc = fmt.Sprintf(autoDescriptionFormat, d.name, cmdDesc)
+		} else {
+			desc := describe(d)
+			switch {
+			case tautology(d, desc):
+				// a description repeating the name says nothing, the comment format is kept
+			case docParagraphs:
+				doc = fmt.Sprintf(autoDescriptionFormat, d.name, terminate(desc.summary))
+				paragraph = desc.paragraph()
+			default:
+				doc = fmt.Sprintf(autoDescriptionFormat, d.name, terminate(desc.String()))
+			}
+		}
+	}
+	lines := formatLines(doc)
+	if paragraph != "" {
+		lines = append(lines, "//")
+		lines = append(lines, wrapComment("// "+paragraph, wrapWidth)...)
+	}
+	return lines
+}
+
+// tautology reports whether desc only repeats the name of d, e.g. "server" for Server.
+func tautology(d declaration, desc description) bool {
+	return strings.EqualFold(desc.String(), d.name)
+}
+
+// formatLines wraps each line of the rendered doc, a template may render several.
+// Empty lines are dropped, so are trailing "//" lines left by template parts rendering nothing,
+// e.g. the parameter line of a function without parameters.
+func formatLines(doc string) []string {
+	var lines []string
+	for _, line := range strings.Split(doc, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, wrapComment(line, wrapWidth)...)
+	}
+	for len(lines) > 1 && strings.TrimSpace(lines[len(lines)-1]) == "//" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// attachedStart returns the index of the first decoration of the comment group directly above
+// the node, the decorations before it are separated from the node by an empty line.
+// A "\n" decoration following a line comment or another "\n" is an empty line,
+// a single one following a block comment only ends its line.
+func attachedStart(decs []string) int {
+	for i := len(decs) - 1; i > 0; i-- {
+		if decs[i] == "\n" && (decs[i-1] == "\n" || strings.HasPrefix(decs[i-1], "//")) {
+			return i + 1
+		}
+	}
+	if len(decs) > 0 && decs[0] == "\n" {
+		return 1
+	}
+	return 0
+}
+
+// isConstructor reports whether d is a function NewX whose first result is X or *X.
+func isConstructor(d declaration) bool {
+	if d.kind != kindFunc || !strings.HasPrefix(d.name, "New") || len(d.name) == len("New") || len(d.results) == 0 {
+		return false
+	}
+	return strings.TrimPrefix(d.results[0], "*") == strings.TrimPrefix(d.name, "New")
+}
+
+// isDirective reports whether the comment is a directive rather than documentation,
+// e.g. "//line file.go:10" or "//go:generate", following the rules of go/ast.
+func isDirective(comment string) bool {
+	if strings.HasPrefix(comment, "//line ") || strings.HasPrefix(comment, "/*line ") ||
+		strings.HasPrefix(comment, "//extern ") || strings.HasPrefix(comment, "//export ") {
+		return true
+	}
+	text := strings.TrimPrefix(comment, "//")
+	if text == comment {
+		return false
+	}
+	colon := strings.Index(text, ":")
+	if colon <= 0 || colon+1 >= len(text) {
+		return false
+	}
+	for i := 0; i <= colon+1; i++ {
+		if i == colon {
+			continue
+		}
+		b := text[i]
+		if !('a' <= b && b <= 'z' || '0' <= b && b <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// leadingDirectives returns the number of directives the decorations start with,
+// stopping at the first comment which could be documentation. Linter directives such as
+// //nolint are counted too, whether or not they name linters.
+func leadingDirectives(decs []string) int {
+	lead := 0
+	for lead < len(decs) && (isDirective(decs[lead]) || isNolint(decs[lead])) {
+		lead++
+	}
+	return lead
+}
+
+// isNolint reports whether the comment is a //nolint directive, e.g. "//nolint" or "//nolint:errcheck // reason".
+func isNolint(comment string) bool {
+	return comment == "//nolint" || strings.HasPrefix(comment, "//nolint:") || strings.HasPrefix(comment, "//nolint ")
+}
+
+// hasLineDirective reports whether the file contains a //line directive.
+func hasLineDirective(file *ast.File) bool {
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, "//line ") || strings.HasPrefix(c.Text, "/*line ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// collapseBlanks returns the line comments of doc with runs of blank "//" lines collapsed to a single one.
+func collapseBlanks(doc []string) []string {
+	blank := func(c string) bool {
+		return strings.HasPrefix(c, "//") && strings.TrimSpace(strings.TrimPrefix(c, "//")) == ""
+	}
+	var lines []string
+	for i, c := range doc {
+		if i > 0 && blank(c) && blank(doc[i-1]) {
+			continue
+		}
+		lines = append(lines, c)
+	}
+	return lines
+}
+
+// lineComments returns decs with block comments such as "/* Name does x */" rewritten as line comments,
+// and whether one of them is on the line of the declaration, i.e. not followed by a line break.
+func lineComments(decs []string) ([]string, bool) {
+	var lines []string
+	inline := false
+	for i := 0; i < len(decs); i++ {
+		dec := decs[i]
+		if !strings.HasPrefix(dec, "/*") || isDirective(dec) {
+			lines = append(lines, dec)
+			continue
+		}
+		lines = append(lines, blockLines(dec)...)
+		// the line break following a line comment is implied
+		if i+1 < len(decs) && decs[i+1] == "\n" {
+			i++
+		} else {
+			inline = true
+		}
+	}
+	return lines, inline
+}
+
+// blockLines returns the text of the block comment as line comments, leading "*" of the lines
+// and empty lines at the start and the end are dropped.
+func blockLines(comment string) []string {
+	text := strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "*") {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		}
+		if line == "" {
+			lines = append(lines, "//")
+		} else {
+			lines = append(lines, "// "+line)
+		}
+	}
+	for len(lines) > 0 && lines[0] == "//" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "//" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func trimPrefix(doc, name string) string {
+	if text, ok := afterNameSeparator(doc, name); ok {
+		return text
+	}
+	cases := []string{
+		// trim '//Name '
+		fmt.Sprintf("//%s ", name),
+		// trim '// Name: '
+		fmt.Sprintf("// %s: ", name),
+		// trim '// Name:'
+		fmt.Sprintf("// %s:", name),
+		// trim '//Name: '
+		fmt.Sprintf("//%s: ", name),
+		// trim '//Name:'
+		fmt.Sprintf("//%s:", name),
+		// trim '// '
+		fmt.Sprintf("// "),
+		// trim '//'
+		fmt.Sprintf("//"),
+	}
+	for _, c := range cases {
+		if strings.HasPrefix(doc, c) {
+			return strings.TrimPrefix(doc, c)
+		}
+	}
+	return doc
+}
+
+// afterNameSeparator returns the text of the first line of a doc following the name and the separators
+// of --name-separators, e.g. "does x" for "// Name - does x", with --normalize-first-line-name.
+func afterNameSeparator(doc, name string) (string, bool) {
+	if !normalizeNames {
+		return "", false
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(doc, "//"), " ")
+	if !strings.HasPrefix(rest, name) {
+		return "", false
+	}
+	rest = rest[len(name):]
+	found := false
+	for {
+		trimmed := strings.TrimLeft(rest, " ")
+		sep := ""
+		for _, s := range nameSeparators {
+			if strings.HasPrefix(trimmed, s) && separated(trimmed[len(s):]) {
+				sep = s
+				break
+			}
+		}
+		if sep == "" {
+			break
+		}
+		rest, found = trimmed[len(sep):], true
+	}
+	return strings.TrimLeft(rest, " "), found
+}
+
+// separated reports whether the text following a separator leaves it apart from a word,
+// so "G-Force" is not taken for the name G followed by a dash.
+func separated(text string) bool {
+	if text == "" || text[0] == ' ' {
+		return true
+	}
+	for _, s := range nameSeparators {
+		if strings.HasPrefix(text, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// mock doc, split the Name to single word
+func mockDoc(name string) string {
+	results := Split(name)
+	for i, r := range results {
+		results[i] = strings.ToLower(r)
+	}
+	return strings.Join(results, " ")
+}
+
+// Filter excluding go test files from directory
+func testsFilter(entry fs.DirEntry) bool {
+	return includeTests || !strings.HasSuffix(entry.Name(), "_test.go")
+}
+
+// sizeFilter skips files larger than --max-file-bytes, such as generated files lacking the marker.
+func sizeFilter(fileName string, entry fs.DirEntry) bool {
+	if maxFileBytes <= 0 {
+		return true
+	}
+	info, err := entry.Info()
+	if err != nil || info.Size() <= maxFileBytes {
+		return true
+	}
+	if verbose {
+		log.Printf("Skipping file %s of %d bytes, larger than %d", fileName, info.Size(), maxFileBytes)
+	}
+	return false
+}
+
+// isTestingFunc reports whether the function name is a test, benchmark, fuzz target or example run by go test,
+// given whether it is a method and the types of its parameters and results.
+// As go test does, both the name and the signature are checked, e.g. TestHelper() is a regular function.
+func isTestingFunc(name string, method bool, params, results []string) bool {
+	if method || len(results) > 0 {
+		return false
+	}
+	switch {
+	case name == "TestMain":
+		return len(params) == 1 && params[0] == "*testing.M"
+	case isTestName(name, "Test"):
+		return len(params) == 1 && params[0] == "*testing.T"
+	case isTestName(name, "Benchmark"):
+		return len(params) == 1 && params[0] == "*testing.B"
+	case isTestName(name, "Fuzz"):
+		return len(params) == 1 && params[0] == "*testing.F"
+	case isTestName(name, "Example"):
+		return len(params) == 0
+	}
+	return false
+}
+
+// isTestName reports whether name is prefix followed by nothing or a non lower case letter,
+// e.g. "TestFoo" but not "Testify".
+func isTestName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
+
+// Filter excluding generated go files from directory.
+// Generated file is considered a file which matches one of the following:
+// 1. The name of the file contains "generated"
+// 2. First line of the file contains "generated" or "GENERATED"
+func generatedFilter(fsys fs.FS, dir string, entry fs.DirEntry) bool {
+	if strings.Contains(entry.Name(), "generated") {
+		return false
+	}
+
+	f, err := fsys.Open(path.Join(dir, entry.Name()))
+	if err != nil {
+		panic(fmt.Sprintf("Failed opening file %s: %v", path.Join(dir, entry.Name()), err))
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan()
+	return !generatedLine(scanner.Text())
+}
+
+// generatedLine reports whether the first line of a file marks it as generated.
+func generatedLine(line string) bool {
+	return strings.Contains(line, "generated") || strings.Contains(line, "GENERATED")
+}
+
+// mapDirectory runs operation on root and every directory below it except vendor directories,
+// those deeper than --max-depth and in a workspace other modules.
+// The walk stops with the error of ctx once it is done, the directory being processed is completed first.
+func mapDirectory(ctx context.Context, fsys fs.FS, root string, operation func(context.Context, fs.FS, string) error) error {
+	// selected records for every walked directory whether its module is processed,
+	// directories inherit it from their parent unless they are the root of a module
+	selected := make(map[string]bool)
+	return fs.WalkDir(fsys, root,
+		func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if entry.Name() == "vendor" {
+				return fs.SkipDir
+			}
+			if entry.IsDir() && isNestedModule(fsys, root, path) {
+				return fs.SkipDir
+			}
+
+			if entry.IsDir() {
+				if maxDepth >= 0 && depth(root, path) > maxDepth {
+					if verbose {
+						log.Printf("Skipping directory %s deeper than %d", path, maxDepth)
+					}
+					return fs.SkipDir
+				}
+				selected[path] = dirSelected(fsys, root, path, selected)
+				if !selected[path] {
+					return nil
+				}
+				return operation(ctx, fsys, path)
+			}
+			return nil
+		})
+}
+
+// dirSelected reports whether dir is processed according to --module and --exclude-module,
+// based on the module owning it. The directories of a nested module are selected on their own
+// and not along with the enclosing module, so every directory is processed at most once.
+func dirSelected(fsys fs.FS, root, dir string, selected map[string]bool) bool {
+	if len(includeModules) == 0 && len(excludeModules) == 0 {
+		return true
+	}
+	if dir == root {
+		modDir, modPath := owningModule(fsys, dir)
+		ok := moduleSelected(modDir, modPath)
+		if !ok && verbose {
+			log.Printf("Skipping directory %s of module %s", dir, modPath)
+		}
+		return ok
+	}
+	if modPath := modulePath(fsys, dir); modPath != "" {
+		ok := moduleSelected(dir, modPath)
+		if !ok && verbose {
+			log.Printf("Skipping module %s in %s", modPath, dir)
+		}
+		return ok
+	}
+	return selected[path.Dir(dir)]
+}
+
+// depth returns how many directories dir lies below root, root itself is at depth 0.
+func depth(root, dir string) int {
+	rel := strings.TrimPrefix(strings.TrimPrefix(dir, root), "/")
+	if root == "." {
+		rel = dir
+	}
+	if rel == "" || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, "/") + 1
+}
+
+// Split missing godoc.
+func Split(src string) (entries []string) {
+	// invalid utf8 cannot come from the parser, replace it so the comment stays valid
+	src = strings.ToValidUTF8(src, string(utf8.RuneError))
+	entries = []string{}
+	var runes [][]rune
+	lastClass := 0
+	class := 0
+	// split into fields based on class of unicode character
+	for _, r := range src {
+		switch true {
+		case isMark(r) && len(runes) > 0:
+			// combining marks belong to the letter before them, e.g. the umlaut of a decomposed "Ü"
+			class = lastClass
+		case unicode.IsLower(r):
+			class = 1
+		case isUpper(r):
+			class = 2
+		case unicode.IsDigit(r):
+			class = 3
+		case unicode.IsLetter(r):
+			// letters without case such as CJK stay together as one word
+			class = 5
+		default:
+			class = 4
+		}
+		if class == lastClass {
+			runes[len(runes)-1] = append(runes[len(runes)-1], r)
+		} else {
+			runes = append(runes, []rune{r})
+		}
+		lastClass = class
+	}
+	// handle upper case -> lower case sequences, e.g.
+	// "PDFL", "oader" -> "PDF", "Loader"
+	for i := 0; i < len(runes)-1; i++ {
+		if isUpper(runes[i][0]) && unicode.IsLower(runes[i+1][0]) {
+			// move the last letter together with its combining marks
+			last := len(runes[i]) - 1
+			for last > 0 && isMark(runes[i][last]) {
+				last--
+			}
+			runes[i+1] = append(append([]rune{}, runes[i][last:]...), runes[i+1]...)
+			runes[i] = runes[i][:last]
+		}
+	}
+	// construct []string from results
+	for _, s := range runes {
+		if len(s) > 0 {
+			entries = append(entries, string(s))
+		}
+	}
+	return
+}
+
+// isUpper reports whether r is an upper case or title case letter, e.g. "ǅ".
+func isUpper(r rune) bool {
+	return unicode.IsUpper(r) || unicode.IsTitle(r)
+}
+
+// isMark reports whether r is a combining mark, which is part of the letter before it.
+func isMark(r rune) bool {
+	return unicode.Is(unicode.M, r)
+}
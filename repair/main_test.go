@@ -0,0 +1,206 @@
+package repair
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// binary is the command built by TestMain, tests run it as users do.
+var binary string
+
+// cacheHome is the user cache directory of the runs, so the journal of the tests stays out of the real one.
+var cacheHome string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "godoc-repair-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	binary = filepath.Join(dir, "godoc-repair")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	cacheHome = filepath.Join(dir, "cache")
+	if out, err := exec.Command("go", "build", "-o", binary, "..").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed building the command: %v\n%s", err, out)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// command returns the command run with args in dir.
+func command(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "XDG_CACHE_HOME="+cacheHome, "HOME="+cacheHome, "LocalAppData="+cacheHome)
+	return cmd
+}
+
+// run runs the command with args in dir and returns its stdout, its stderr and its exit status.
+func run(t testing.TB, dir string, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	cmd := command(dir, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		t.Fatalf("failed running %v: %v", args, err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+// writeTree creates a module holding files, given by their slash separated path, in a temporary directory.
+func writeTree(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if _, ok := files["go.mod"]; !ok {
+		files["go.mod"] = "module fixture\n\ngo 1.18\n"
+	}
+	for name, content := range files {
+		fileName := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// readTree returns the content of the regular files below dir by their slash separated path.
+func readTree(t testing.TB, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(fileName string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, fileName)
+		files[filepath.ToSlash(rel)] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// copyExample creates a module in a temporary directory holding the given files of the example package.
+func copyExample(t testing.TB, names ...string) string {
+	t.Helper()
+	files := make(map[string]string)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join("..", "example", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(data)
+	}
+	return writeTree(t, files)
+}
+
+func TestSplitNonASCII(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Überweisung", []string{"Überweisung"}},
+		// the umlaut as a combining mark stays with its letter
+		{"U\u0308berweisung", []string{"U\u0308berweisung"}},
+		{"ПолучитьДанные", []string{"Получить", "Данные"}},
+		{"ΑλφαΒήτα", []string{"Αλφα", "Βήτα"}},
+		{"HTTPÜberweisung", []string{"HTTP", "Überweisung"}},
+		// letters without case stay together
+		{"Getデータ", []string{"Get", "データ"}},
+		{"Bad\xffName", []string{"Bad", "\uFFFD", "Name"}},
+	}
+	for _, tt := range tests {
+		if got := Split(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Split(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeNonASCII(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": "package p\n\nfunc ПолучитьДанные() {}\n\nfunc GetÜberweisung() {}\n"})
+	if _, stderr, code := run(t, dir, "-auto-description"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["a.go"]
+	for _, want := range []string{"// ПолучитьДанные получить данные.\n", "// GetÜberweisung gets überweisung.\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestAfterNameSeparator(t *testing.T) {
+	savedNormalize, savedSeparators := normalizeNames, nameSeparators
+	t.Cleanup(func() { normalizeNames, nameSeparators = savedNormalize, savedSeparators })
+	normalizeNames, nameSeparators = true, strings.Fields(": - — – ()")
+	tests := []struct {
+		doc, want string
+		ok        bool
+	}{
+		{"// Name: does x.", "does x.", true},
+		{"// Name - does x.", "does x.", true},
+		{"// Name — does x.", "does x.", true},
+		{"// Name – does x.", "does x.", true},
+		{"// Name() does x.", "does x.", true},
+		{"// Name () - does x.", "does x.", true},
+		{"// Name does x.", "", false},
+		// not the name followed by a separator
+		{"// Name-based lookup.", "", false},
+		{"// Names - are x.", "", false},
+		{"// Other - does x.", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := afterNameSeparator(tt.doc, "Name")
+		if ok != tt.ok || ok && got != tt.want {
+			t.Errorf("afterNameSeparator(%q) = %q, %v, want %q, %v", tt.doc, got, ok, tt.want, tt.ok)
+		}
+	}
+	normalizeNames = false
+	if _, ok := afterNameSeparator("// Name - does x.", "Name"); ok {
+		t.Error("separator removed without --normalize-first-line-name")
+	}
+}
+
+func TestNormalizeFirstLineName(t *testing.T) {
+	src := "package p\n\n// A - does a.\nfunc A() {}\n\n// B — does b.\nfunc B() {}\n\n// C() does c.\nfunc C() {}\n\n// D does d.\nfunc D() {}\n"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-normalize-first-line-name"}, "// A does a.\nfunc A() {}\n\n// B does b.\nfunc B() {}\n\n// C does c.\nfunc C() {}\n\n// D does d.\n"},
+		{[]string{"-normalize-first-line-name", "-name-separators", "—"}, "// A - does a.\nfunc A() {}\n\n// B does b.\nfunc B() {}\n"},
+		{nil, "// A - does a.\nfunc A() {}\n\n// B — does b.\nfunc B() {}\n"},
+	}
+	for _, tt := range tests {
+		dir := writeTree(t, map[string]string{"a.go": src})
+		if _, stderr, code := run(t, dir, tt.args...); code != 0 {
+			t.Fatalf("%v: exit status %d: %s", tt.args, code, stderr)
+		}
+		if got := readTree(t, dir)["a.go"]; !strings.Contains(got, tt.want) {
+			t.Errorf("%v: a.go lacks %q:\n%s", tt.args, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package repair
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Actions of the edits listed in the manifest.
+const (
+	actionInserted  = "inserted"
+	actionRewritten = "rewritten"
+)
+
+// edit is a doc inserted or rewritten by the run, listed in the manifest written by --manifest.
+type edit struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Symbol string `json:"symbol"`
+	Kind   string `json:"kind"`
+	Action string `json:"action"`
+	// Rules are the ids of the rules whose fixes make up the edit.
+	Rules []string `json:"rules,omitempty"`
+	Text  string   `json:"text"`
+}
+
+// fileEdits collects the edits of the file being repaired.
+var fileEdits []edit
+
+// recordEdit records that the doc of d was inserted or rewritten as lines by the fixes of rules.
+// The line is only known once the file is printed, see resolveEdits.
+func recordEdit(d declaration, action string, lines, rules []string) {
+	fileEdits = append(fileEdits, edit{Symbol: d.symbol(), Kind: d.kind, Action: action, Rules: rules, Text: strings.Join(lines, "\n")})
+}
+
+// resolveEdits returns edits with the file name and the line of the docs in the repaired src.
+func resolveEdits(fileName string, src []byte, edits []edit) ([]edit, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	lines := map[string]int{kindPackage + " " + file.Name.Name: fset.Position(file.Package).Line}
+	if file.Doc != nil {
+		lines[kindPackage+" "+file.Name.Name] = fset.Position(file.Doc.Pos()).Line
+	}
+	eachDecl(fset, file, func(d declaration, doc *ast.CommentGroup) {
+		line := d.pos.Line
+		if doc != nil {
+			line = fset.Position(doc.Pos()).Line
+		}
+		lines[d.kind+" "+d.symbol()] = line
+	})
+	resolved := make([]edit, 0, len(edits))
+	for _, e := range edits {
+		e.File = fileName
+		e.Line = lines[e.Kind+" "+e.Symbol]
+		resolved = append(resolved, e)
+	}
+	return resolved, nil
+}
+
+// manifest lists the edits applied by a run.
+type manifest struct {
+	// Options is the hash of the options of the run, as used by the cache.
+	Options string `json:"options"`
+	Time    string `json:"time"`
+	Edits   []edit `json:"edits"`
+}
+
+// writeManifest writes the edits of the applied changes to fileName, nothing is written when there are none.
+func writeManifest(fileName string) error {
+	m := manifest{Options: optionsHash(), Time: time.Now().UTC().Format(time.RFC3339)}
+	for _, c := range pending {
+		m.Edits = append(m.Edits, c.edits...)
+	}
+	if len(m.Edits) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed encoding manifest: %v", err)
+	}
+	dir := filepath.Dir(fileName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed creating directory %s: %v", dir, err)
+	}
+	if err := newDirFS(dir).Write(filepath.Base(fileName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed writing manifest %s: %v", fileName, err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package repair
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// linterName is the linter name //nolint comments list to suppress the findings and fixes of this tool.
+const linterName = "godocrepair"
+
+// suppressed collects the findings suppressed by //nolint comments in check mode.
+var suppressed []finding
+
+// suppresses reports whether comment is a //nolint directive covering this tool, as golangci-lint reads them:
+// one without a list of linters, or one listing godocrepair or all, e.g. "//nolint:godocrepair // legacy API".
+func suppresses(comment string) bool {
+	if !isNolint(comment) {
+		return false
+	}
+	rest := strings.TrimPrefix(comment, "//nolint")
+	if !strings.HasPrefix(rest, ":") {
+		return true
+	}
+	list := strings.Fields(strings.TrimPrefix(rest, ":"))
+	if len(list) == 0 {
+		return false
+	}
+	for _, name := range strings.Split(list[0], ",") {
+		if name == linterName || name == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+// anySuppresses reports whether one of comments suppresses this tool.
+func anySuppresses(comments []string) bool {
+	for _, c := range comments {
+		if suppresses(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// nolintScope holds where //nolint comments of a file suppress this tool.
+type nolintScope struct {
+	// file is set by a comment on the line of the package clause, which covers the whole file.
+	file bool
+	// lines holds the physical lines of comments, which cover a declaration starting on them.
+	lines map[int]bool
+}
+
+// newNolintScope returns the scope of the //nolint comments of file.
+func newNolintScope(fset *token.FileSet, file *ast.File) nolintScope {
+	scope := nolintScope{lines: make(map[int]bool)}
+	pkgLine := fset.Position(file.Package).Line
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if !suppresses(c.Text) {
+				continue
+			}
+			line := fset.Position(c.Slash).Line
+			scope.lines[line] = true
+			if line == pkgLine {
+				scope.file = true
+			}
+		}
+	}
+	return scope
+}
+
+// covers reports whether the declaration starting at pos is suppressed, by a comment on the package clause
+// or on the line it starts on. Comments in the doc of a declaration are looked at along with the doc.
+func (s nolintScope) covers(fset *token.FileSet, pos token.Pos) bool {
+	return s.file || s.lines[fset.Position(pos).Line]
+}
@@ -0,0 +1,64 @@
+package repair
+
+import "testing"
+
+// A //nolint covering this tool takes precedence over --doc-below-directives: the declaration is left alone
+// rather than documented below the directive.
+func TestNolintPrecedence(t *testing.T) {
+	tests := []struct {
+		directive string
+		want      string
+	}{
+		{"//nolint", ""},
+		{"//nolint:all", ""},
+		{"//nolint:errcheck,godocrepair", ""},
+		{"//nolint:errcheck", "//nolint:errcheck\n// Run missing godoc.\nfunc Run() {}\n"},
+		{"//go:generate stringer -type=T", "//go:generate stringer -type=T\n// Run missing godoc.\nfunc Run() {}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.directive, func(t *testing.T) {
+			src := "package p\n\n" + tt.directive + "\nfunc Run() {}\n"
+			dir := writeTree(t, map[string]string{"a.go": src})
+			stdout, _, code := run(t, dir, "-check")
+			if got := code == 0; got != (tt.want == "") {
+				t.Errorf("-check exit status %d, findings %q", code, stdout)
+			}
+			if _, stderr, code := run(t, dir, "-doc-below-directives"); code != 0 {
+				t.Fatalf("exit status %d: %s", code, stderr)
+			}
+			want := src
+			if tt.want != "" {
+				want = "package p\n\n" + tt.want
+			}
+			if got := readTree(t, dir)["a.go"]; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// Directives stay in the comment group of the declaration wherever the doc goes, and a //nolint covering
+// this tool leaves its declaration without doc in both placements.
+func TestDocBelowDirectives(t *testing.T) {
+	src := "package p\n\n//go:generate stringer -type=Level\n//nolint:errcheck\ntype Level int\n\n//nolint:all\nfunc Skip() {}\n"
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "// Level missing godoc.\n//go:generate stringer -type=Level\n//nolint:errcheck\ntype Level int\n"},
+		{[]string{"-doc-below-directives"}, "//go:generate stringer -type=Level\n//nolint:errcheck\n// Level missing godoc.\ntype Level int\n"},
+	} {
+		dir := writeTree(t, map[string]string{"a.go": src})
+		if _, stderr, code := run(t, dir, tt.args...); code != 0 {
+			t.Fatalf("%v: exit status %d: %s", tt.args, code, stderr)
+		}
+		want := "package p\n\n" + tt.want + "\n//nolint:all\nfunc Skip() {}\n"
+		if got := readTree(t, dir)["a.go"]; got != want {
+			t.Errorf("%v: got\n%s\nwant\n%s", tt.args, got, want)
+		}
+		// the inserted doc is taken for the doc of Level, Skip stays suppressed
+		if stdout, _, code := run(t, dir, "-check"); code != 0 {
+			t.Errorf("%v: exit status %d, findings:\n%s", tt.args, code, stdout)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package repair
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// targetOffsets holds the offsets of the declarations repaired in -offset mode, nil otherwise.
+var targetOffsets map[int]bool
+
+// parseOffset splits an -offset argument of the form file.go:#1234 into the file name and byte offset.
+func parseOffset(arg string) (string, int, error) {
+	i := strings.LastIndex(arg, ":#")
+	if i < 0 {
+		return "", 0, fmt.Errorf("invalid offset %q, expected file.go:#offset", arg)
+	}
+	offset, err := strconv.Atoi(arg[i+2:])
+	if err != nil || offset < 0 {
+		return "", 0, fmt.Errorf("invalid offset %q, expected file.go:#offset", arg)
+	}
+	return arg[:i], offset, nil
+}
+
+// runOffset repairs the doc of the declaration of the file enclosing the byte offset given as
+// file.go:#1234 and writes the whole file to out, or the LSP text edits when output is "edits".
+// An offset outside of any declaration leaves the file unchanged.
+func runOffset(ctx context.Context, arg, output string, out io.Writer) error {
+	fileName, offset, err := parseOffset(arg)
+	if err != nil {
+		return err
+	}
+	src, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("failed reading file %s: %v", fileName, err)
+	}
+	result, err := repairDeclaration(ctx, fileName, src, offset)
+	if err != nil {
+		return err
+	}
+	if output == "edits" {
+		return json.NewEncoder(out).Encode(textEdits(src, result))
+	}
+	_, err = out.Write(result)
+	return err
+}
+
+// Options configures Declaration, the zero value repairs as the command does without flags.
+type Options struct {
+	// Format is the comment format of missing docs, see --format, empty for the placeholder of Lang.
+	Format string
+	// AutoDescription describes declarations from their name and signature, see --auto-description.
+	AutoDescription bool
+	// Lang is the language of the generated phrases, see --lang, empty for English.
+	Lang string
+	// FileName names the source in errors, e.g. "store.go".
+	FileName string
+}
+
+// libraryLock serializes Declaration, Directory and FS, which work on the state of the package as a run
+// of the command does. It is a channel so that waiting for it ends with the context of the call.
+var libraryLock = make(chan struct{}, 1)
+
+// lockLibrary waits for libraryLock and returns the function releasing it, or the error of ctx once done.
+func lockLibrary(ctx context.Context) (func(), error) {
+	select {
+	case libraryLock <- struct{}{}:
+		return func() { <-libraryLock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Declaration repairs the doc of the exported declaration of the Go file src enclosing the byte offset,
+// e.g. for an editor documenting the symbol under the cursor, and returns the updated source.
+// src is returned unchanged when the offset is not inside a declaration or the declaration needs no repair.
+// The command line flags do not apply to calls. As they work on the global state of the package, calls are
+// serialized with each other and with Directory and FS, a call waiting for another one returns the error
+// of ctx once it is done, as does a call canceled while repairing.
+func Declaration(ctx context.Context, src []byte, offset int, opts Options) ([]byte, error) {
+	unlock, err := lockLibrary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := configure(opts); err != nil {
+		return nil, err
+	}
+	fileName := opts.FileName
+	if fileName == "" {
+		fileName = "source.go"
+	}
+	return repairDeclaration(ctx, fileName, src, offset)
+}
+
+// configure sets up the package state the command derives from its flags for opts.
+func configure(opts Options) error {
+	lang := opts.Lang
+	if lang == "" {
+		lang = flags.Lookup("lang").DefValue
+	}
+	m, err := loadCatalog(lang)
+	if err != nil {
+		return err
+	}
+	catalog = m
+	// setting the values directly keeps the flags unset, as they would be without a command line
+	for name, key := range catalogFlags {
+		if err := flags.Lookup(name).Value.Set(catalog[key]); err != nil {
+			return err
+		}
+	}
+	if opts.Format != "" {
+		commentFormat = opts.Format
+	}
+	tmpl, err := parseFormat(commentFormat)
+	if err != nil {
+		return fmt.Errorf("invalid comment format: %v", err)
+	}
+	commentTemplate = tmpl
+	autoDescription = opts.AutoDescription
+	nameSeparators = strings.Fields(nameSeparatorsArg)
+	return configureRules("", "", false)
+}
+
+// repairDeclaration repairs the doc of the declaration of the file src enclosing the byte offset
+// with the options of the run and returns the updated source. The source is returned unchanged
+// when the offset is not inside a declaration or the declaration needs no repair.
+// fileName is only used in positions and errors.
+func repairDeclaration(ctx context.Context, fileName string, src []byte, offset int) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if offset < 0 || offset > len(src) {
+		return nil, fmt.Errorf("offset %d is outside of %s", offset, fileName)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	symbols = buildSymbols(&ast.Package{Name: file.Name.Name, Files: map[string]*ast.File{fileName: file}})
+	targetOffsets = enclosingDecls(fset, file, offset)
+	defer func() { targetOffsets = nil }()
+	if len(targetOffsets) == 0 {
+		return src, nil
+	}
+	var buf bytes.Buffer
+	if err := instrumentFile(ctx, fset, file, false, &buf); err != nil {
+		return nil, fmt.Errorf("failed instrumenting file %s: %v", fileName, err)
+	}
+	// a description command canceled on the way leaves its declaration undescribed
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// enclosingDecls returns the offsets of the declarations documented separately which enclose offset,
+// their doc comment included: the spec of a group enclosing it, or every spec when it is on the group itself.
+func enclosingDecls(fset *token.FileSet, file *ast.File, offset int) map[int]bool {
+	encloses := func(doc *ast.CommentGroup, n ast.Node) bool {
+		start := n.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		return fset.Position(start).Offset <= offset && offset < fset.Position(n.End()).Offset
+	}
+	offsets := make(map[int]bool)
+	for _, decl := range file.Decls {
+		switch t := decl.(type) {
+		case *ast.FuncDecl:
+			if encloses(t.Doc, t) {
+				offsets[fset.Position(t.Pos()).Offset] = true
+			}
+		case *ast.GenDecl:
+			if !encloses(t.Doc, t) {
+				continue
+			}
+			for _, spec := range t.Specs {
+				var doc *ast.CommentGroup
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					doc = s.Doc
+				case *ast.ValueSpec:
+					doc = s.Doc
+				}
+				if encloses(doc, spec) {
+					return map[int]bool{fset.Position(spec.Pos()).Offset: true}
+				}
+				offsets[fset.Position(spec.Pos()).Offset] = true
+			}
+		}
+	}
+	return offsets
+}
+
+// textEdit is an LSP TextEdit, positions count lines and UTF-16 code units from zero.
+type textEdit struct {
+	Range struct {
+		Start lspPosition `json:"start"`
+		End   lspPosition `json:"end"`
+	} `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// textEdits returns the edits turning src into result, a single edit replacing what lies between
+// their common prefix and suffix, none if they are equal.
+func textEdits(src, result []byte) []textEdit {
+	edits := []textEdit{}
+	if bytes.Equal(src, result) {
+		return edits
+	}
+	prefix := 0
+	for prefix < len(src) && prefix < len(result) && src[prefix] == result[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(src)-prefix && suffix < len(result)-prefix && src[len(src)-1-suffix] == result[len(result)-1-suffix] {
+		suffix++
+	}
+	// keep the edit on rune boundaries
+	for prefix > 0 && prefix < len(src) && !utf8.RuneStart(src[prefix]) {
+		prefix--
+	}
+	for suffix > 0 && !utf8.RuneStart(src[len(src)-suffix]) {
+		suffix--
+	}
+	var edit textEdit
+	edit.Range.Start = lspPositionOf(src, prefix)
+	edit.Range.End = lspPositionOf(src, len(src)-suffix)
+	edit.NewText = string(result[prefix : len(result)-suffix])
+	return append(edits, edit)
+}
+
+// lspPositionOf returns the LSP position of the byte offset in src.
+func lspPositionOf(src []byte, offset int) lspPosition {
+	var pos lspPosition
+	for _, r := range string(src[:offset]) {
+		if r == '\n' {
+			pos.Line++
+			pos.Character = 0
+			continue
+		}
+		// runes outside of the basic multilingual plane take a surrogate pair
+		if r >= 0x10000 {
+			pos.Character += 2
+		} else {
+			pos.Character++
+		}
+	}
+	return pos
+}
@@ -0,0 +1,82 @@
+package repair
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const declarationSrc = `package store
+
+func Open() {}
+
+func Close() {}
+
+func helper() {}
+`
+
+func TestDeclaration(t *testing.T) {
+	offset := strings.Index(declarationSrc, "Close")
+	got, err := Declaration(context.Background(), []byte(declarationSrc), offset, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(declarationSrc, "func Close", "// Close missing godoc.\nfunc Close", 1)
+	if string(got) != want {
+		t.Errorf("Declaration() = %q, want %q", got, want)
+	}
+}
+
+func TestDeclarationFormat(t *testing.T) {
+	offset := strings.Index(declarationSrc, "Open")
+	got, err := Declaration(context.Background(), []byte(declarationSrc), offset, Options{Format: "// {{.Name}} is a {{.Kind}}."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "// Open is a func.\nfunc Open") || strings.Contains(string(got), "// Close") {
+		t.Errorf("Declaration() = %q, want only Open documented with the format", got)
+	}
+}
+
+func TestDeclarationUnchanged(t *testing.T) {
+	for name, offset := range map[string]int{
+		"package clause": 0,
+		"unexported":     strings.Index(declarationSrc, "helper"),
+		"end of file":    len(declarationSrc),
+	} {
+		got, err := Declaration(context.Background(), []byte(declarationSrc), offset, Options{})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if string(got) != declarationSrc {
+			t.Errorf("%s: Declaration() = %q, want the source unchanged", name, got)
+		}
+	}
+}
+
+func TestDeclarationOffsetOutside(t *testing.T) {
+	if _, err := Declaration(context.Background(), []byte(declarationSrc), len(declarationSrc)+1, Options{}); err == nil {
+		t.Error("Declaration() succeeded for an offset after the end of the source")
+	}
+}
+
+func TestDeclarationCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Declaration(ctx, []byte(declarationSrc), 0, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	// a call waiting for another one gives up with its context
+	unlock, err := lockLibrary(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Declaration(ctx, []byte(declarationSrc), 0, Options{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,119 @@
+package repair
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// orderFiles is a tree of several packages and files, with several findings on a declaration.
+var orderFiles = map[string]string{
+	"b.go":       "package root\n\nfunc B() {}\n\n// OldName does x\nfunc Renamed() {}\n",
+	"a.go":       "package root\n\nfunc A() {}\n\ntype T struct{}\n\nfunc (T) M() {}\n",
+	"c.go":       "package root\n\nconst C = 1\n",
+	"sub/z.go":   "package sub\n\nvar Z = 1\n",
+	"sub/y.go":   "package sub\n\nfunc Y() {}\n",
+	"other/x.go": "package other\n\n// X is documented.\nfunc X() {}\n",
+}
+
+// orderOutputs returns the outputs of each report format for a fresh copy of orderFiles.
+func orderOutputs(t *testing.T) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	for name, content := range orderFiles {
+		files[name] = content
+	}
+	dir := writeTree(t, files)
+	common := []string{"-check", "-quiet-success", "-verify-name-match", "-enable", "ends-with-period"}
+	outputs := make(map[string]string)
+	for _, format := range []string{"text", "csv", "junit", "codeclimate"} {
+		args := common
+		if format != "text" {
+			args = append(args[:len(args):len(args)], "-output", format)
+		}
+		stdout, stderr, _ := run(t, dir, args...)
+		if stderr != "" {
+			t.Fatalf("%s: unexpected logs %q", format, stderr)
+		}
+		outputs[format] = stdout
+	}
+	report := filepath.Join(t.TempDir(), "report.json")
+	run(t, dir, append(common, "-report-out", report)...)
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs["json"] = string(data)
+	return outputs
+}
+
+func TestOutputDeterministic(t *testing.T) {
+	first, second := orderOutputs(t), orderOutputs(t)
+	for format, out := range first {
+		if out == "" {
+			t.Errorf("%s: empty output", format)
+		}
+		if out != second[format] {
+			t.Errorf("%s: outputs of two runs differ:\n%s\n---\n%s", format, out, second[format])
+		}
+	}
+}
+
+func TestOutputSorted(t *testing.T) {
+	outputs := orderOutputs(t)
+	lines := strings.Split(strings.TrimSuffix(outputs["text"], "\n"), "\n")
+	want := []string{
+		"a.go:3:1: func A missing godoc",
+		"a.go:5:6: type T missing godoc",
+		"a.go:7:1: method T.M missing godoc",
+		"b.go:3:1: func B missing godoc",
+		"b.go:6:1: func Renamed godoc does not end with a period or other final punctuation [ends-with-period]",
+		"b.go:6:1: func Renamed godoc starts with OldName instead of its name [name-mismatch]",
+		"c.go:3:7: const C missing godoc",
+		"sub/y.go:3:1: func Y missing godoc",
+		"sub/z.go:3:5: var Z missing godoc",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("findings are not sorted by file, position and rule:\n%s", outputs["text"])
+	}
+	// the other formats list the same findings in the same order
+	for format, key := range map[string]string{"csv": "b.go,6,Renamed,func,", "junit": `file="b.go" line="6"`, "codeclimate": `"path":"b.go","lines":{"begin":6}`, "json": `"line": 6`} {
+		positions := positionsIn(outputs[format], key)
+		if len(positions) != 2 || positions[0] > positions[1] {
+			t.Errorf("%s: want two findings of Renamed, got %d", format, len(positions))
+		}
+		if !inOrder(outputs[format], "a.go", "b.go", "c.go", "sub/y.go", "sub/z.go") {
+			t.Errorf("%s: files are not in order:\n%s", format, outputs[format])
+		}
+		if strings.Index(outputs[format], "ends-with-period") > strings.Index(outputs[format], "name-mismatch") {
+			t.Errorf("%s: findings of a declaration are not sorted by rule:\n%s", format, outputs[format])
+		}
+	}
+}
+
+// positionsIn returns the offsets of the occurrences of key in s.
+func positionsIn(s, key string) []int {
+	var positions []int
+	for i := 0; ; {
+		j := strings.Index(s[i:], key)
+		if j < 0 {
+			return positions
+		}
+		positions = append(positions, i+j)
+		i += j + len(key)
+	}
+}
+
+// inOrder reports whether the first occurrences of keys in s are in the order given.
+func inOrder(s string, keys ...string) bool {
+	last := -1
+	for _, key := range keys {
+		i := strings.Index(s, key)
+		if i <= last {
+			return false
+		}
+		last = i
+	}
+	return true
+}
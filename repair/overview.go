@@ -0,0 +1,77 @@
+package repair
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"path"
+	"sort"
+)
+
+// typeMethods records the doc state of a type and its methods for --require-type-overview.
+type typeMethods struct {
+	pos        token.Position
+	name       string
+	documented bool
+	methods    int
+	// undocumented are the methods without doc, in order of position.
+	undocumented []declaration
+}
+
+// overviews holds the types of the checked packages by package path and name.
+var overviews = make(map[string]*typeMethods)
+
+// overviewOf returns the record of the type name of the package of d.
+func overviewOf(d declaration, name string) *typeMethods {
+	key := packagePath(path.Dir(d.pos.Filename), d.pkg) + "." + name
+	if overviews[key] == nil {
+		overviews[key] = &typeMethods{name: name}
+	}
+	return overviews[key]
+}
+
+// recordOverview records the doc state of d if it is a type or a method.
+func recordOverview(d declaration, missing bool) {
+	switch d.kind {
+	case kindType:
+		t := overviewOf(d, d.name)
+		t.pos, t.documented = d.pos, !missing
+	case kindMethod:
+		t := overviewOf(d, d.recvType)
+		t.methods++
+		if missing {
+			t.undocumented = append(t.undocumented, d)
+		}
+	}
+}
+
+// printOverview prints the documented types with undocumented methods, by file and position,
+// each followed by the positions of these methods.
+func printOverview(out io.Writer) {
+	var partial []*typeMethods
+	for _, t := range overviews {
+		if t.documented && len(t.undocumented) > 0 {
+			partial = append(partial, t)
+		}
+	}
+	sort.Slice(partial, func(i, j int) bool {
+		a, b := partial[i].pos, partial[j].pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	for _, t := range partial {
+		fmt.Fprintf(out, "%s: type %s is partially documented, %d of %d methods have no doc:\n", t.pos, t.name, len(t.undocumented), t.methods)
+		sort.SliceStable(t.undocumented, func(i, j int) bool {
+			a, b := t.undocumented[i].pos, t.undocumented[j].pos
+			if a.Filename != b.Filename {
+				return a.Filename < b.Filename
+			}
+			return a.Offset < b.Offset
+		})
+		for _, d := range t.undocumented {
+			fmt.Fprintf(out, "\t%s: method %s\n", d.pos, d.symbol())
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows || plan9
+
+package repair
+
+import "io/fs"
+
+// fileOwner returns false, files have no numeric owner on this system.
+func fileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,17 @@
+//go:build !windows && !plan9
+
+package repair
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileOwner returns the user and group owning the file of info, ok is false when the system does not tell.
+func fileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
@@ -0,0 +1,112 @@
+package repair
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// patchContext is the number of unchanged lines around the changes of a hunk, as in the default of diff -u.
+const patchContext = 3
+
+// writePatch writes the pending changes to fileName as a unified diff applicable with git apply or patch -p1
+// from the code path, instead of applying them.
+func writePatch(fileName string) error {
+	var buf bytes.Buffer
+	for _, c := range pending {
+		src, err := fs.ReadFile(c.fsys, c.fileName)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed reading file %s: %v", c.fileName, err)
+		}
+		if contentHash(src) != c.source && !(c.source == "" && errors.Is(err, fs.ErrNotExist)) {
+			return fmt.Errorf("failed diffing file %s: it was modified during the run", c.fileName)
+		}
+		fmt.Fprintf(&buf, "diff --git a/%s b/%s\n", c.fileName, c.fileName)
+		from := "a/" + c.fileName
+		if c.source == "" {
+			fmt.Fprintf(&buf, "new file mode 100%o\n", c.perm)
+			from = "/dev/null"
+		}
+		fmt.Fprintf(&buf, "--- %s\n+++ b/%s\n", from, c.fileName)
+		writeHunks(&buf, patchLines(src), patchLines(c.data))
+	}
+	dir := filepath.Dir(fileName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed creating directory %s: %v", dir, err)
+	}
+	if err := newDirFS(dir).Write(filepath.Base(fileName), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed writing patch %s: %v", fileName, err)
+	}
+	return nil
+}
+
+// patchLines splits data in lines keeping their line feed, so a missing one at the end of the file is a change.
+func patchLines(data []byte) []string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// writeHunks writes the hunks turning the lines a into b, changes closer than twice patchContext sharing a hunk.
+func writeHunks(buf *bytes.Buffer, a, b []string) {
+	edits := diffLines(a, b)
+	// shift is the difference between the line numbers of b and a before each edit
+	shift := make([]int, len(edits)+1)
+	for i, e := range edits {
+		shift[i+1] = shift[i] + len(e.Insert) - e.Delete
+	}
+	for first := 0; first < len(edits); {
+		last := first
+		for last+1 < len(edits) && edits[last+1].Line-(edits[last].Line+edits[last].Delete) <= 2*patchContext {
+			last++
+		}
+		start := edits[first].Line - 1 - patchContext
+		if start < 0 {
+			start = 0
+		}
+		end := edits[last].Line - 1 + edits[last].Delete + patchContext
+		if end > len(a) {
+			end = len(a)
+		}
+		var body []string
+		pos := start
+		for _, e := range edits[first : last+1] {
+			for ; pos < e.Line-1; pos++ {
+				body = append(body, " "+a[pos])
+			}
+			for ; pos < e.Line-1+e.Delete; pos++ {
+				body = append(body, "-"+a[pos])
+			}
+			for _, line := range e.Insert {
+				body = append(body, "+"+line)
+			}
+		}
+		for ; pos < end; pos++ {
+			body = append(body, " "+a[pos])
+		}
+		aLen := end - start
+		bLen := aLen + shift[last+1] - shift[first]
+		fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(start, aLen), hunkRange(start+shift[first], bLen))
+		for _, line := range body {
+			buf.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		first = last + 1
+	}
+}
+
+// hunkRange formats the lines of a hunk starting at the index start, an empty range naming the line before it.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
@@ -0,0 +1,32 @@
+package repair
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPatchOutApplies(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	patched, fixed := writeTree(t, undoFiles), writeTree(t, undoFiles)
+	before := readTree(t, patched)
+	patch := filepath.Join(t.TempDir(), "docs.patch")
+	if _, stderr, code := run(t, patched, "-auto-description", "-examples", "-patch-out", patch); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if after := readTree(t, patched); !reflect.DeepEqual(after, before) {
+		t.Fatalf("writing the patch changed files: %q", after)
+	}
+	fix(t, fixed, "-auto-description", "-examples")
+	cmd := exec.Command("git", "apply", patch)
+	cmd.Dir = patched
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git apply failed: %v\n%s", err, out)
+	}
+	if after, want := readTree(t, patched), readTree(t, fixed); !reflect.DeepEqual(after, want) {
+		t.Errorf("applied patch gives\n%q\nwant the files repaired in place\n%q", after, want)
+	}
+}
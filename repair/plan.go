@@ -0,0 +1,187 @@
+package repair
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// plan holds the changes of a run computed by --plan, to be applied later by --apply.
+type plan struct {
+	// Options is the hash of the options the plan was computed with, as used by the cache.
+	Options string        `json:"options"`
+	Files   []plannedFile `json:"files"`
+}
+
+// plannedFile holds the changes of a file.
+type plannedFile struct {
+	// File is relative to the code path.
+	File string `json:"file"`
+	// Hash is the hash of the content the edits apply to, empty for a file which does not exist yet.
+	Hash  string     `json:"hash"`
+	Edits []planEdit `json:"edits"`
+}
+
+// planEdit replaces Delete lines starting at Line, counting from 1, with the Insert lines.
+type planEdit struct {
+	Line   int      `json:"line"`
+	Delete int      `json:"delete"`
+	Insert []string `json:"insert"`
+}
+
+// writePlan writes the pending changes to fileName instead of applying them.
+func writePlan(fileName string) error {
+	p := plan{Options: optionsHash(), Files: []plannedFile{}}
+	for _, c := range pending {
+		src, err := fs.ReadFile(c.fsys, c.fileName)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed reading file %s: %v", c.fileName, err)
+		}
+		if contentHash(src) != c.source && !(c.source == "" && errors.Is(err, fs.ErrNotExist)) {
+			return fmt.Errorf("failed planning file %s: it was modified during the run", c.fileName)
+		}
+		p.Files = append(p.Files, plannedFile{
+			File:  c.fileName,
+			Hash:  c.source,
+			Edits: diffLines(strings.Split(string(src), "\n"), strings.Split(string(c.data), "\n")),
+		})
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed encoding plan: %v", err)
+	}
+	dir := filepath.Dir(fileName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed creating directory %s: %v", dir, err)
+	}
+	if err := newDirFS(dir).Write(filepath.Base(fileName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed writing plan %s: %v", fileName, err)
+	}
+	return nil
+}
+
+// loadPlan reads the plan of fileName and stages its changes to the files of fsys.
+// Nothing is staged if any file changed since the plan was computed, the error lists all of them.
+func loadPlan(fsys writeFS, fileName string) error {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("failed reading plan %s: %v", fileName, err)
+	}
+	var p plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("failed parsing plan %s: %v", fileName, err)
+	}
+	var drifted []string
+	var changes []change
+	for _, f := range p.Files {
+		src, err := fs.ReadFile(fsys, f.File)
+		perm := fs.FileMode(0644)
+		if errors.Is(err, fs.ErrNotExist) {
+			if f.Hash != "" {
+				drifted = append(drifted, f.File+" was removed")
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed reading file %s: %v", f.File, err)
+		} else if f.Hash != contentHash(src) {
+			drifted = append(drifted, f.File+" was modified")
+		} else if info, err := fs.Stat(fsys, f.File); err == nil {
+			perm = info.Mode().Perm()
+		}
+		lines := applyEdits(strings.Split(string(src), "\n"), f.Edits)
+		changes = append(changes, change{fsys: fsys, fileName: f.File, source: f.Hash, data: []byte(strings.Join(lines, "\n")), perm: perm})
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("refusing to apply plan %s, files changed since it was computed:\n  %s", fileName, strings.Join(drifted, "\n  "))
+	}
+	pending = append(pending, changes...)
+	return nil
+}
+
+// applyEdits returns lines with edits applied, the edits are in order and do not overlap.
+func applyEdits(lines []string, edits []planEdit) []string {
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		start := e.Line - 1
+		rest := append([]string{}, lines[start+e.Delete:]...)
+		lines = append(append(lines[:start], e.Insert...), rest...)
+	}
+	return lines
+}
+
+// diffLines returns the edits turning the lines a into b, found with the Myers diff algorithm.
+func diffLines(a, b []string) []planEdit {
+	n, m := len(a), len(b)
+	max := n + m
+	v := make([]int, 2*max+2)
+	// trace holds v before each step, to walk the shortest edit script back
+	var trace [][]int
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int{}, v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[max+k-1] < v[max+k+1] {
+				x = v[max+k+1]
+			} else {
+				x = v[max+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// deleted and inserted record the lines of a which are removed and of b which are added
+	deleted, inserted := make([]bool, n), make([]bool, m)
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || k != d && v[max+k-1] < v[max+k+1] {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[max+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+		}
+		if x == prevX {
+			inserted[prevY] = true
+		} else {
+			deleted[prevX] = true
+		}
+		x, y = prevX, prevY
+	}
+
+	edits := []planEdit{}
+	for i, j := 0, 0; i < n || j < m; {
+		if i < n && j < m && !deleted[i] && !inserted[j] {
+			i++
+			j++
+			continue
+		}
+		e := planEdit{Line: i + 1, Insert: []string{}}
+		for ; i < n && deleted[i]; i++ {
+			e.Delete++
+		}
+		for ; j < m && inserted[j]; j++ {
+			e.Insert = append(e.Insert, b[j])
+		}
+		edits = append(edits, e)
+	}
+	return edits
+}
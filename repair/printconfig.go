@@ -0,0 +1,44 @@
+package repair
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"sort"
+)
+
+// config is the effective configuration of a run printed by --print-config.
+type config struct {
+	// Flags maps every flag to its value, phrases left out on the command line hold those of --lang.
+	Flags map[string]string `json:"flags"`
+	// Given lists the flags given on the command line.
+	Given []string `json:"given"`
+	// Rules maps the enabled rules to their severity.
+	Rules map[string]string `json:"rules"`
+}
+
+// givenFlags returns the sorted names of the flags set so far, to be called before defaults are set from the catalog.
+func givenFlags() []string {
+	names := []string{}
+	flags.Visit(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	sort.Strings(names)
+	return names
+}
+
+// printConfig prints the effective configuration of the run as JSON, given lists the flags of the command line.
+func printConfig(out io.Writer, given []string) error {
+	c := config{Flags: make(map[string]string), Given: given, Rules: make(map[string]string)}
+	flags.VisitAll(func(f *flag.Flag) {
+		c.Flags[f.Name] = f.Value.String()
+	})
+	for _, id := range ruleIDs() {
+		if ruleEnabled(id) {
+			c.Rules[id] = ruleSeverity(id)
+		}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
@@ -0,0 +1,41 @@
+package repair
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPrintConfig(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": "package p\n\nfunc A() {}\n"})
+	stdout, stderr, code := run(t, dir, "-print-config", "-lang", "ja", "-func-format", "// %s does.",
+		"-enable", "ends-with-period,start-with-name", "-disable", "start-with-name", "-severity", "ends-with-period=warning")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	var c config
+	if err := json.Unmarshal([]byte(stdout), &c); err != nil {
+		t.Fatalf("%v:\n%s", err, stdout)
+	}
+	for name, want := range map[string]string{
+		// given flags override the defaults of the catalog, those left out hold the ones of --lang
+		"func-format": "// %s does.",
+		"format":      "// %s のドキュメントはありません。",
+		"lang":        "ja",
+		"check":       "false",
+	} {
+		if got := c.Flags[name]; got != want {
+			t.Errorf("flag %s = %q, want %q", name, got, want)
+		}
+	}
+	if want := []string{"disable", "enable", "func-format", "lang", "print-config", "severity"}; !reflect.DeepEqual(c.Given, want) {
+		t.Errorf("given = %q, want %q", c.Given, want)
+	}
+	if want := map[string]string{ruleMissingDoc: "error", "ends-with-period": "warning"}; !reflect.DeepEqual(c.Rules, want) {
+		t.Errorf("rules = %q, want %q", c.Rules, want)
+	}
+	// the configuration is only printed
+	if got := readTree(t, dir)["a.go"]; got != "package p\n\nfunc A() {}\n" {
+		t.Errorf("a.go was changed:\n%s", got)
+	}
+}
@@ -0,0 +1,76 @@
+package repair
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// jsonReport is the report of a run written by --report-out.
+type jsonReport struct {
+	Findings []jsonFinding `json:"findings"`
+	// Suppressed are the findings suppressed by //nolint comments.
+	Suppressed []jsonFinding `json:"suppressed"`
+	// Changed lists the files changed by the run, relative to the code path.
+	Changed []string `json:"changed"`
+}
+
+// jsonFinding is a finding of the check mode in the JSON report.
+type jsonFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Package  string `json:"package"`
+	Symbol   string `json:"symbol"`
+	Kind     string `json:"kind"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+}
+
+// newReport returns the report of the findings and pending changes of the run.
+func newReport() jsonReport {
+	r := jsonReport{Findings: jsonFindings(findings), Suppressed: jsonFindings(suppressed), Changed: []string{}}
+	for _, c := range pending {
+		r.Changed = append(r.Changed, c.fileName)
+	}
+	sort.Strings(r.Changed)
+	return r
+}
+
+// jsonFindings returns list sorted by position as findings of the JSON report.
+func jsonFindings(list []finding) []jsonFinding {
+	sortByPosition(list)
+	out := []jsonFinding{}
+	for _, f := range list {
+		out = append(out, jsonFinding{
+			File:     f.pos.Filename,
+			Line:     f.pos.Line,
+			Column:   f.pos.Column,
+			Package:  f.pkg,
+			Symbol:   f.symbol,
+			Kind:     f.kind,
+			Rule:     f.rule,
+			Severity: ruleSeverity(f.rule),
+		})
+	}
+	return out
+}
+
+// writeReport writes the JSON report of the run to fileName, creating its parent directories.
+// The file is replaced atomically, so readers never see a partial report.
+func writeReport(fileName string) error {
+	data, err := json.MarshalIndent(newReport(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed encoding report: %v", err)
+	}
+	dir := filepath.Dir(fileName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed creating directory %s: %v", dir, err)
+	}
+	if err := newDirFS(dir).Write(filepath.Base(fileName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed writing report %s: %v", fileName, err)
+	}
+	return nil
+}
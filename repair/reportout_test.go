@@ -0,0 +1,50 @@
+package repair
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readReport(t *testing.T, fileName string) jsonReport {
+	t.Helper()
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r jsonReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatalf("report %s: %v", fileName, err)
+	}
+	return r
+}
+
+func TestReportOut(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.go":     "package p\n\nfunc A() {}\n\n// B does b.\nfunc B() {}\n",
+		"sub/c.go": "package sub\n\n//nolint:godocrepair\nfunc C() {}\n",
+	})
+	// parent directories are created
+	report := filepath.Join(t.TempDir(), "reports", "ci", "report.json")
+	if _, stderr, code := run(t, dir, "-check", "-report-out", report); code != 1 {
+		t.Fatalf("exit status %d, want 1: %s", code, stderr)
+	}
+	want := jsonReport{
+		Findings:   []jsonFinding{{File: "a.go", Line: 3, Column: 1, Package: "p", Symbol: "A", Kind: kindFunc, Rule: ruleMissingDoc, Severity: "error"}},
+		Suppressed: []jsonFinding{{File: "sub/c.go", Line: 4, Column: 1, Package: "sub", Symbol: "C", Kind: kindFunc, Rule: ruleMissingDoc, Severity: "error"}},
+		Changed:    []string{},
+	}
+	if got := readReport(t, report); !reflect.DeepEqual(got, want) {
+		t.Errorf("report = %+v, want %+v", got, want)
+	}
+
+	// a later run replaces the report, listing the files it changed
+	if _, stderr, code := run(t, dir, "-report-out", report); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if got := readReport(t, report); !reflect.DeepEqual(got.Changed, []string{"a.go"}) {
+		t.Errorf("changed = %q, want [a.go]", got.Changed)
+	}
+}
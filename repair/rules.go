@@ -0,0 +1,235 @@
+package repair
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rule ids of findings and fixes.
+const (
+	ruleMissingDoc     = "missing-doc"
+	rulePlaceholderDoc = "placeholder-doc"
+	ruleStartWithName  = "start-with-name"
+	ruleEndsWithPeriod = "ends-with-period"
+	ruleNonTrivial     = "non-trivial"
+	ruleNameMismatch   = "name-mismatch"
+)
+
+// Styles of --comment-style, which enable the rules their docs follow.
+const (
+	styleGodoc    = "godoc"
+	styleSentence = "sentence"
+)
+
+// Severities of rules.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// ruleInfo is a rule of the registry.
+type ruleInfo struct {
+	id string
+	// message describes the findings of the rule.
+	message string
+	// enabled tells whether the rule is enabled by default.
+	enabled bool
+}
+
+// registry lists the rules in the order they are documented.
+var registry = []ruleInfo{
+	{id: ruleMissingDoc, message: "missing godoc", enabled: true},
+	{id: rulePlaceholderDoc, message: "has a placeholder godoc"},
+	{id: ruleStartWithName, message: "godoc does not start with its name"},
+	{id: ruleEndsWithPeriod, message: "godoc does not end with a period or other final punctuation"},
+	{id: ruleNonTrivial, message: "godoc only holds its name"},
+	{id: ruleNameMismatch, message: "godoc starts with another name"},
+}
+
+// severities maps rules to the severity set with --severity, rules missing from it are errors.
+var severities = map[string]string{}
+
+// parseSeverities parses the comma separated rule=severity pairs of --severity.
+func parseSeverities(list string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		id, severity, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid severity %q, expected rule=severity", pair)
+		}
+		if _, known := enabledRules[id]; !known {
+			return nil, fmt.Errorf("unknown rule %q, expected one of %s", id, strings.Join(ruleIDs(), ", "))
+		}
+		if severity != severityError && severity != severityWarning {
+			return nil, fmt.Errorf("invalid severity %q of rule %s, expected %s or %s", severity, id, severityError, severityWarning)
+		}
+		m[id] = severity
+	}
+	return m, nil
+}
+
+// ruleSeverity returns the severity of the findings of rule id.
+func ruleSeverity(id string) string {
+	if severity, ok := severities[id]; ok {
+		return severity
+	}
+	return severityError
+}
+
+// failing reports whether findings hold one making the run fail: one of error severity,
+// or any with --warnings-as-errors.
+func failing(findings []finding) bool {
+	for _, f := range findings {
+		if warningsAsErrors || ruleSeverity(f.rule) == severityError {
+			return true
+		}
+	}
+	return false
+}
+
+// enabledRules is the set of rules enabled for the run, see configureRules.
+var enabledRules = defaultRules()
+
+// defaultRules returns the set of rules enabled by default.
+func defaultRules() map[string]bool {
+	rules := make(map[string]bool)
+	for _, r := range registry {
+		rules[r.id] = r.enabled
+	}
+	return rules
+}
+
+// ruleEnabled reports whether the rule id is enabled, findings and fixes of disabled rules are left out.
+func ruleEnabled(id string) bool {
+	return enabledRules[id]
+}
+
+// ruleMessage returns the description of the findings of the rule id.
+func ruleMessage(id string) string {
+	for _, r := range registry {
+		if r.id == id {
+			return r.message
+		}
+	}
+	return id
+}
+
+// configureRules enables all rules with all, then those of the comma separated lists enable
+// and disables those of disable, on top of the default set.
+func configureRules(enable, disable string, all bool) error {
+	enabledRules = defaultRules()
+	if all {
+		for id := range enabledRules {
+			enabledRules[id] = true
+		}
+	}
+	for _, change := range []struct {
+		list    string
+		enabled bool
+	}{{enable, true}, {disable, false}} {
+		if change.list == "" {
+			continue
+		}
+		for _, id := range strings.Split(change.list, ",") {
+			id = strings.TrimSpace(id)
+			if _, ok := enabledRules[id]; !ok {
+				return fmt.Errorf("unknown rule %q, expected one of %s", id, strings.Join(ruleIDs(), ", "))
+			}
+			enabledRules[id] = change.enabled
+		}
+	}
+	return nil
+}
+
+// ruleIDs returns the ids of the registry.
+func ruleIDs() []string {
+	ids := make([]string, 0, len(registry))
+	for _, r := range registry {
+		ids = append(ids, r.id)
+	}
+	return ids
+}
+
+// startsWithArticle reports whether the first line of a doc starts with an article followed by name,
+// e.g. "// A Client talks to the server.", which start-with-name accepts.
+func startsWithArticle(first, name string) bool {
+	for _, article := range []string{"A", "An", "The"} {
+		if strings.HasPrefix(first, fmt.Sprintf("// %s %s ", article, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// staleName returns the name the first line of a doc starts with when it is not name, e.g. "OldName"
+// for "// OldName does x" left by a rename, or "Client.OldName" for a method. Only words which cannot
+// start a sentence count as names, see identLike, qualified identifiers such as "Store.Fetch" and the names
+// declared by the package.
+func staleName(first, name string) (string, bool) {
+	fields := strings.Fields(strings.TrimPrefix(first, "//"))
+	if len(fields) == 0 {
+		return "", false
+	}
+	word := strings.TrimRight(fields[0], ":,()")
+	dot := strings.LastIndex(word, ".")
+	ident := word[dot+1:]
+	if ident == name || !token.IsIdentifier(ident) {
+		return "", false
+	}
+	if symbols.names[ident] || identLike(ident) || dot > 0 && token.IsIdentifier(word[:dot]) {
+		return word, true
+	}
+	return "", false
+}
+
+// identLike reports whether word mixes lower and upper case after its first letter, as in "GetUser",
+// or holds digits or underscores.
+func identLike(word string) bool {
+	lower, upper := false, false
+	for i, r := range word {
+		switch {
+		case !unicode.IsLetter(r):
+			return true
+		case i == 0:
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		}
+	}
+	return lower && upper
+}
+
+// lastDocLine returns the index of the last line of doc holding text, -1 if there is none.
+func lastDocLine(doc []string) int {
+	for i := len(doc) - 1; i >= 0; i-- {
+		if strings.HasPrefix(doc[i], "//") && strings.TrimSpace(strings.TrimPrefix(doc[i], "//")) != "" && !isDirective(doc[i]) && !isNolint(doc[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sentenceEnds are the characters a doc may end with for ends-with-period, a colon e.g. introducing the values
+// of a const block, along with their full-width forms used by the ja catalog.
+const sentenceEnds = ".!?:。！？："
+
+// endsWithPunctuation reports whether the text of doc ends with a period or other final punctuation of sentenceEnds.
+func endsWithPunctuation(doc []string) bool {
+	i := lastDocLine(doc)
+	if i < 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(strings.TrimSpace(doc[i]))
+	return strings.ContainsRune(sentenceEnds, r)
+}
+
+// addPeriod ends the text of doc with a period, as the auto-fix of ends-with-period.
+func addPeriod(doc []string) {
+	if i := lastDocLine(doc); i >= 0 && !endsWithPunctuation(doc) {
+		doc[i] = strings.TrimRight(doc[i], " \t") + "."
+	}
+}
@@ -0,0 +1,128 @@
+package repair
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEndsWithPunctuation(t *testing.T) {
+	for _, c := range []struct {
+		doc  []string
+		want bool
+	}{
+		{[]string{"// Run runs."}, true},
+		{[]string{"// Run runs!"}, true},
+		{[]string{"// Run runs?"}, true},
+		{[]string{"// Run runs the steps:"}, true},
+		{[]string{"// Run runs.  "}, true},
+		{[]string{"// Run は実行します。"}, true},
+		{[]string{"// Run runs"}, false},
+		{[]string{"// Run runs,"}, false},
+		{[]string{"// Run runs.", "//nolint:all"}, true},
+		{nil, true},
+	} {
+		if got := endsWithPunctuation(c.doc); got != c.want {
+			t.Errorf("endsWithPunctuation(%q) = %v, want %v", c.doc, got, c.want)
+		}
+	}
+}
+
+func TestEndsWithPeriodRule(t *testing.T) {
+	src := "package run\n\n// Run runs!\nfunc Run() {}\n\n// Stop stops\nfunc Stop() {}\n\n// Level is one of the levels below:\ntype Level int\n"
+	dir := writeTree(t, map[string]string{"run.go": src})
+	stdout, _, _ := run(t, dir, "-check", "-quiet-success", "-enable", "ends-with-period")
+	want := "run.go:7:1: func Stop godoc does not end with a period or other final punctuation [ends-with-period]\n"
+	if stdout != want {
+		t.Errorf("findings %q, want %q", stdout, want)
+	}
+	run(t, dir, "-quiet-success", "-enable", "ends-with-period")
+	if got, want := readTree(t, dir)["run.go"], strings.Replace(src, "// Stop stops\n", "// Stop stops.\n", 1); got != want {
+		t.Errorf("repaired file:\n%s\nwant\n%s", got, want)
+	}
+}
+
+// Descriptions written by --auto-description pass ends-with-period, whatever the kind of declaration.
+func TestAutoDescriptionEndsWithPeriod(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": `package store
+
+type UserStore struct {
+	MaxUsers int
+}
+
+func NewUserStore(path string, opts ...string) *UserStore { return nil }
+
+func (s *UserStore) Close() error { return nil }
+
+func (s *UserStore) FindUser(name string) string { return name }
+
+func Open() {}
+
+const DefaultPath = "users.db"
+
+var OnError func(error)
+
+type Users []UserStore
+`})
+	if _, stderr, code := run(t, dir, "-auto-description"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	stdout, _, _ := run(t, dir, "-check", "-strict", "all")
+	if strings.Contains(stdout, "[ends-with-period]") {
+		t.Errorf("generated docs do not end with a period:\n%s", stdout)
+	}
+}
+
+func TestStaleName(t *testing.T) {
+	saved := symbols
+	t.Cleanup(func() { symbols = saved })
+	symbols = packageSymbols{names: map[string]bool{"Close": true}}
+	for _, c := range []struct {
+		first, want string
+		ok          bool
+	}{
+		{"// OldName does x.", "OldName", true},
+		{"// Client.OldName does x.", "Client.OldName", true},
+		{"// Store.Fetch gets.", "Store.Fetch", true},
+		{"// Parse2: parses.", "Parse2", true},
+		{"// E.g. parses.", "", false},
+		// a name declared by the package
+		{"// Close closes.", "Close", true},
+		{"// Open opens.", "", false},
+		{"// Returns the user.", "", false},
+		{"// NewName does x.", "", false},
+		{"//", "", false},
+	} {
+		got, ok := staleName(c.first, "NewName")
+		if got != c.want || ok != c.ok {
+			t.Errorf("staleName(%q) = %q, %v, want %q, %v", c.first, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestVerifyNameMatch(t *testing.T) {
+	src := "package p\n\n// OldName does x.\nfunc NewName() {}\n\n// Match does y.\nfunc Match() {}\n\n" +
+		"type Store struct{}\n\n// Store.Fetch gets.\nfunc (s *Store) Get() {}\n\n// Returns z.\nfunc Z() {}\n"
+	dir := writeTree(t, map[string]string{"a.go": src})
+	stdout, _, code := run(t, dir, "-verify-name-match", "-quiet-success", "-only-kinds", "func,method")
+	if code != 1 {
+		t.Errorf("exit status %d, want 1", code)
+	}
+	for _, want := range []string{
+		"a.go:4:1: func NewName godoc starts with OldName instead of its name [name-mismatch]\n",
+		"a.go:12:1: method Store.Get godoc starts with Store.Fetch instead of its name [name-mismatch]\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("findings lack %q:\n%s", want, stdout)
+		}
+	}
+	// a sentence starting with another word is no stale name
+	for _, symbol := range []string{"func Match", "func Z godoc starts"} {
+		if strings.Contains(stdout, symbol) {
+			t.Errorf("%s is reported:\n%s", symbol, stdout)
+		}
+	}
+	// the check only reports
+	if got := readTree(t, dir)["a.go"]; got != src {
+		t.Errorf("a.go was changed:\n%s", got)
+	}
+}
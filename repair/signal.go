@@ -0,0 +1,29 @@
+package repair
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exitInterrupted is the exit code of a run interrupted by SIGINT or SIGTERM.
+const exitInterrupted = 130
+
+// cancelOnSignal returns a context canceled on the first SIGINT or SIGTERM, so the run stops
+// before writing anything. A second signal exits at once, files being replaced atomically
+// a file is either written or left as it was.
+func cancelOnSignal(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Print("interrupted, stopping, interrupt again to exit immediately")
+		cancel()
+		<-signals
+		exit(exitInterrupted)
+	}()
+	return ctx
+}
@@ -0,0 +1,52 @@
+package repair
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+)
+
+// stampComment marks files modified by the tool when --stamp is given.
+const stampComment = "// Code partially documented by godoc-repair."
+
+// stampFile inserts the stamp comment on its own after the package clause of src, leaving build
+// constraints and the package comment above it untouched. Files already stamped are returned as they are.
+func stampFile(src []byte) ([]byte, error) {
+	if hasStamp(src) {
+		return src, nil
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.PackageClauseOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing package clause: %v", err)
+	}
+	// the stamp goes after the line of the package clause, which may end in a comment
+	end := fset.Position(f.Name.End()).Offset
+	if i := bytes.IndexByte(src[end:], '\n'); i >= 0 {
+		end += i + 1
+	} else {
+		src = append(src, '\n')
+		end = len(src)
+	}
+	// empty lines around the stamp keep it from being taken as the doc of what follows
+	stamp := "\n" + stampComment + "\n"
+	if !bytes.HasPrefix(src[end:], []byte("\n")) {
+		stamp += "\n"
+	}
+	var buf bytes.Buffer
+	buf.Write(src[:end])
+	buf.WriteString(stamp)
+	buf.Write(src[end:])
+	return buf.Bytes(), nil
+}
+
+// hasStamp reports whether src contains the stamp comment on a line of its own.
+func hasStamp(src []byte) bool {
+	for _, line := range bytes.Split(src, []byte("\n")) {
+		if string(bytes.TrimSpace(line)) == stampComment {
+			return true
+		}
+	}
+	return false
+}
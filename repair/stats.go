@@ -0,0 +1,129 @@
+package repair
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+)
+
+const (
+	// maxSummaryRows is the number of packages listed by the summary table, the others are only counted.
+	maxSummaryRows = 20
+	// maxPackageWidth is the width of the package column, longer paths are truncated from the left.
+	maxPackageWidth = 40
+)
+
+// packageStats counts the exported declarations of a package processed by the run.
+type packageStats struct {
+	name string
+	// declared counts the declarations, missing those whose doc needs repair by kind.
+	declared int
+	missing  map[string]int
+	// files counts the files written by the run, see applyChanges.
+	files int
+}
+
+// stats holds the statistics of each package processed by the run, by packagePath.
+var stats = make(map[string]*packageStats)
+
+// statsOf returns the statistics of the package name of the file fileName.
+func statsOf(fileName, name string) *packageStats {
+	key := packagePath(path.Dir(fileName), name)
+	if stats[key] == nil {
+		stats[key] = &packageStats{name: key, missing: make(map[string]int)}
+	}
+	return stats[key]
+}
+
+// countDecl counts the declaration d, as missing a doc when missing is set, i.e. when --check reports
+// a finding for it or its doc is repaired.
+func countDecl(d declaration, missing bool) {
+	if d.kind == kindPackage {
+		return
+	}
+	s := statsOf(d.pos.Filename, d.pkg)
+	s.declared++
+	if missing {
+		s.missing[d.kind]++
+	}
+}
+
+// missingDocs returns the number of declarations of s without a doc.
+func (s *packageStats) missingDocs() int {
+	n := 0
+	for _, count := range s.missing {
+		n += count
+	}
+	return n
+}
+
+// coverage returns the percentage of the declarations of s with a doc.
+func (s *packageStats) coverage() string {
+	if s.declared == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(s.declared-s.missingDocs())/float64(s.declared))
+}
+
+// isTerminal reports whether f is a terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printSummary prints the statistics of the run, a table of the packages sorted by missing docs
+// when stderr is a terminal, a single line otherwise.
+func printSummary() {
+	if len(stats) == 0 {
+		return
+	}
+	total := packageStats{name: "total", missing: make(map[string]int)}
+	var list []*packageStats
+	for _, s := range stats {
+		list = append(list, s)
+		total.declared += s.declared
+		total.files += s.files
+		for kind, n := range s.missing {
+			total.missing[kind] += n
+		}
+	}
+	if !isTerminal(os.Stderr) {
+		logProgress("Summary: %d packages, %d declarations, %d missing docs, %s documented, %d files changed",
+			len(list), total.declared, total.missingDocs(), total.coverage(), total.files)
+		return
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].missingDocs() != list[j].missingDocs() {
+			return list[i].missingDocs() > list[j].missingDocs()
+		}
+		return list[i].name < list[j].name
+	})
+	printTable(os.Stderr, list, &total)
+}
+
+// printTable prints the rows of list, at most maxSummaryRows, followed by total.
+func printTable(out io.Writer, list []*packageStats, total *packageStats) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tFUNCS\tTYPES\tCONSTS\tVARS\tCOVERAGE\tFILES")
+	row := func(s *packageStats) {
+		name := s.name
+		if r := []rune(name); len(r) > maxPackageWidth {
+			name = "…" + string(r[len(r)-maxPackageWidth+1:])
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\t%d\n", name,
+			s.missing[kindFunc]+s.missing[kindMethod], s.missing[kindType]+s.missing[kindField],
+			s.missing[kindConst], s.missing[kindVar], s.coverage(), s.files)
+	}
+	for i, s := range list {
+		if i == maxSummaryRows {
+			fmt.Fprintf(w, "and %d more packages\n", len(list)-i)
+			break
+		}
+		row(s)
+	}
+	row(total)
+	w.Flush()
+}
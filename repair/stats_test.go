@@ -0,0 +1,55 @@
+package repair
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var summaryPattern = regexp.MustCompile(`Summary: \d+ packages, \d+ declarations, (\d+) missing docs, .* documented, (\d+) files changed`)
+
+// summary returns the missing docs and the files changed of the summary logged in stderr.
+func summary(t *testing.T, stderr string) (int, int) {
+	t.Helper()
+	m := summaryPattern.FindStringSubmatch(stderr)
+	if m == nil {
+		t.Fatalf("no summary logged: %s", stderr)
+	}
+	missing, _ := strconv.Atoi(m[1])
+	files, _ := strconv.Atoi(m[2])
+	return missing, files
+}
+
+// exampleFiles are the files of the example package.
+var exampleFiles = []string{"auto_doc.go", "levels.go", "limits.go", "store.go", "store_methods.go"}
+
+func TestSummaryMatchesCheck(t *testing.T) {
+	dir := copyExample(t, exampleFiles...)
+	stdout, stderr, _ := run(t, dir, "-check")
+	findings := strings.Count(stdout, "\n")
+	if missing, files := summary(t, stderr); missing != findings || files != 0 {
+		t.Errorf("check summary has %d missing docs and %d files changed, want %d and 0", missing, files, findings)
+	}
+	for _, args := range [][]string{
+		{"-plan", filepath.Join(t.TempDir(), "plan.json")},
+		{"-patch-out", filepath.Join(t.TempDir(), "docs.patch")},
+	} {
+		_, stderr, _ := run(t, dir, args...)
+		if missing, files := summary(t, stderr); missing != findings || files != 0 {
+			t.Errorf("%s summary has %d missing docs and %d files changed, want %d and 0", args[0], missing, files, findings)
+		}
+	}
+	_, stderr, _ = run(t, dir)
+	if missing, files := summary(t, stderr); missing != findings || files != len(exampleFiles) {
+		t.Errorf("summary has %d missing docs and %d files changed, want %d and %d", missing, files, findings, len(exampleFiles))
+	}
+	// the placeholders left are not reported by --check, nor counted when repairing again
+	for _, args := range [][]string{{"-check"}, nil} {
+		_, stderr, _ := run(t, dir, args...)
+		if missing, files := summary(t, stderr); missing != 0 || files != 0 {
+			t.Errorf("summary of %v over the repaired tree has %d missing docs and %d files changed, want none", args, missing, files)
+		}
+	}
+}
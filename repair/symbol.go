@@ -0,0 +1,88 @@
+package repair
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// stringList holds the values of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// onlyKinds is the set of kinds given with --only-kinds, nil when all kinds are processed.
+var onlyKinds map[string]bool
+
+// parseKinds parses the comma separated kinds of --only-kinds.
+func parseKinds(list string) (map[string]bool, error) {
+	kinds := make(map[string]bool)
+	for _, kind := range strings.Split(list, ",") {
+		kind = strings.TrimSpace(kind)
+		switch kind {
+		case kindType, kindFunc, kindMethod, kindConst, kindVar, kindPackage, kindField:
+			kinds[kind] = true
+		default:
+			return nil, fmt.Errorf("unknown kind %q, expected type, func, method, const, var, package or field", kind)
+		}
+	}
+	return kinds, nil
+}
+
+// symbolsFound records the -symbol arguments which matched a declaration.
+var symbolsFound = make(map[string]bool)
+
+// selected reports whether d is processed, always unless --only-kinds or -symbol restrict the run.
+// Symbols are given as Name, Type.Method, pkg.Name or path/pkg.Name, where path is matched against
+// the end of the directory of the declaration, e.g. "internal/storage.Client.Close".
+func selected(d declaration) bool {
+	if onlyKinds != nil && !onlyKinds[d.kind] {
+		return false
+	}
+	if len(onlySymbols) == 0 {
+		return true
+	}
+	symbol := d.symbol()
+	ok := false
+	for _, arg := range onlySymbols {
+		if matchSymbol(arg, d, symbol) {
+			symbolsFound[arg] = true
+			ok = true
+		}
+	}
+	return ok
+}
+
+// matchSymbol reports whether the -symbol argument arg refers to d named symbol in its package.
+func matchSymbol(arg string, d declaration, symbol string) bool {
+	slash := strings.LastIndex(arg, "/")
+	if slash < 0 {
+		return arg == symbol || arg == d.pkg+"."+symbol
+	}
+	if arg[slash+1:] != d.pkg+"."+symbol {
+		return false
+	}
+	dir := path.Join(arg[:slash], d.pkg)
+	fileDir := path.Dir(d.pos.Filename)
+	return fileDir == dir || strings.HasSuffix(fileDir, "/"+dir)
+}
+
+// missingSymbols returns the -symbol arguments which matched no declaration.
+func missingSymbols() []string {
+	var missing []string
+	for _, arg := range onlySymbols {
+		if !symbolsFound[arg] {
+			missing = append(missing, arg)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
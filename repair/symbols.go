@@ -0,0 +1,94 @@
+package repair
+
+import (
+	"go/ast"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// packageSymbols is the symbol table of the package being repaired, built from all of its files
+// before any of them is instrumented, as the methods of a type are often spread across files.
+type packageSymbols struct {
+	// names are the exported package-level names.
+	names map[string]bool
+	// imports are the names the files refer to imported packages by, importPaths these names by import path.
+	imports     map[string]bool
+	importPaths map[string]string
+	// interfaces are the exported interfaces by name.
+	interfaces map[string]*ast.InterfaceType
+	// methods maps receiver type names to the signatures of their methods by name.
+	methods map[string]map[string]string
+}
+
+// symbols is the symbol table of the package being repaired.
+var symbols packageSymbols
+
+// buildSymbols collects the symbol table of pkg.
+func buildSymbols(pkg *ast.Package) packageSymbols {
+	s := packageSymbols{
+		names:       make(map[string]bool),
+		imports:     make(map[string]bool),
+		importPaths: make(map[string]string),
+		interfaces:  make(map[string]*ast.InterfaceType),
+		methods:     make(map[string]map[string]string),
+	}
+	for _, file := range pkg.Files {
+		for name, obj := range file.Scope.Objects {
+			if obj.Kind != ast.Pkg && token.IsExported(name) {
+				s.names[name] = true
+			}
+		}
+		for _, spec := range file.Imports {
+			s.imports[importName(spec)] = true
+			if p, err := strconv.Unquote(spec.Path.Value); err == nil {
+				s.importPaths[p] = importName(spec)
+			}
+		}
+		for _, decl := range file.Decls {
+			switch t := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range t.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok || !token.IsExported(ts.Name.Name) {
+						continue
+					}
+					if iface, ok := ts.Type.(*ast.InterfaceType); ok {
+						s.interfaces[ts.Name.Name] = iface
+					}
+				}
+			case *ast.FuncDecl:
+				if t.Recv == nil {
+					continue
+				}
+				recv := recvTypeName(t.Recv)
+				if s.methods[recv] == nil {
+					s.methods[recv] = make(map[string]string)
+				}
+				s.methods[recv][t.Name.Name] = signature(t.Type)
+			}
+		}
+	}
+	return s
+}
+
+// importName returns the name a file refers to the imported package by, the last element of
+// its path unless it is renamed, e.g. "yaml" for "gopkg.in/yaml.v3" and "chi" for ".../chi/v5".
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	p, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return ""
+	}
+	name := path.Base(p)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = path.Base(path.Dir(p))
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
@@ -0,0 +1,119 @@
+package repair
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// verbs are the words conjugated when a function name starts with them, e.g. CreateUser -> "creates user".
+// More can be added with -verbs.
+var verbs = map[string]bool{}
+
+func init() {
+	for _, verb := range strings.Fields(`
+		accept add allocate append apply assert attach bind build cache calculate call cancel check clear
+		clone close collect compare compile compute configure connect convert copy count create decode
+		delete destroy detach dial disable disconnect dispatch do drain drop emit enable encode ensure
+		execute exec expand fetch fill filter find finish flush format generate get go handle have hash
+		init initialize insert install invoke join list listen load lock log lookup make map mark marshal
+		match merge migrate move notify open parse patch ping pop post print process publish pull push
+		put query read receive record register reload remove rename render replace reset resolve restore
+		retry return run save scan schedule search send serve set shutdown sort split start stop store
+		subscribe sync trim truncate try uninstall unlock unmarshal unregister unsubscribe unwrap update
+		upload use validate verify visit wait walk watch wrap write
+	`) {
+		verbs[verb] = true
+	}
+}
+
+// irregularVerbs are conjugated from this table rather than by suffix rules.
+var irregularVerbs = map[string]string{
+	"be":   "is",
+	"do":   "does",
+	"go":   "goes",
+	"have": "has",
+}
+
+// phrases map the first word of a function name to a phrase template, {rest} is replaced
+// by the remaining words, e.g. ValidateConfig -> "checks that config is valid".
+// More can be added or the built-in ones overridden with -phrases.
+var phrases = map[string]string{
+	"delete":   "removes {rest}",
+	"ensure":   "makes sure that {rest}",
+	"validate": "checks that {rest} is valid",
+	"list":     "returns all {rest}",
+}
+
+// phrase describes the words of a function name with the phrase of its first word,
+// falling back to conjugating the first word. A name made of the first word only is conjugated.
+func phrase(words string) string {
+	i := strings.Index(words, " ")
+	if i < 0 {
+		return conjugate(words)
+	}
+	template, ok := phrases[words[:i]]
+	if !ok {
+		return conjugate(words)
+	}
+	return strings.ReplaceAll(template, "{rest}", words[i+1:])
+}
+
+// loadPhrases reads a JSON file mapping first words to phrase templates into phrases.
+func loadPhrases(fileName string) error {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("failed reading phrases %s: %v", fileName, err)
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("failed parsing phrases %s: %v", fileName, err)
+	}
+	for word, template := range m {
+		if !strings.Contains(template, "{rest}") {
+			return fmt.Errorf("phrase %q for %q does not contain {rest}", template, word)
+		}
+		phrases[strings.ToLower(word)] = template
+	}
+	return nil
+}
+
+// conjugate puts the first word of words in the third person singular if it is a known verb,
+// e.g. "create user" -> "creates user". "is" and "are" are kept as they are, as is anything else.
+func conjugate(words string) string {
+	first, rest := words, ""
+	if i := strings.Index(words, " "); i >= 0 {
+		first, rest = words[:i], words[i:]
+	}
+	// a first word keeping its casing, e.g. as the name of a type of the package, is still conjugated as a verb
+	verb := strings.ToLower(first)
+	if !verbs[verb] || isAcronym(first) {
+		return words
+	}
+	return thirdPerson(verb) + rest
+}
+
+// thirdPerson conjugates verb in the third person singular, e.g. "flush" -> "flushes".
+func thirdPerson(verb string) string {
+	if conjugated, ok := irregularVerbs[verb]; ok {
+		return conjugated
+	}
+	switch {
+	case strings.HasSuffix(verb, "s"), strings.HasSuffix(verb, "sh"), strings.HasSuffix(verb, "ch"),
+		strings.HasSuffix(verb, "x"), strings.HasSuffix(verb, "z"), strings.HasSuffix(verb, "o"):
+		return verb + "es"
+	case len(verb) > 1 && strings.HasSuffix(verb, "y") && !strings.ContainsRune("aeiou", rune(verb[len(verb)-2])):
+		return verb[:len(verb)-1] + "ies"
+	}
+	return verb + "s"
+}
+
+// addVerbs adds the comma separated list of verbs to the known verbs.
+func addVerbs(list string) {
+	for _, verb := range strings.Split(list, ",") {
+		if verb = strings.ToLower(strings.TrimSpace(verb)); verb != "" {
+			verbs[verb] = true
+		}
+	}
+}
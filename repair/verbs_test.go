@@ -0,0 +1,44 @@
+package repair
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConjugate(t *testing.T) {
+	for words, want := range map[string]string{
+		"create user":   "creates user",
+		"fetch data":    "fetches data",
+		"copy buffer":   "copies buffer",
+		"apply patch":   "applies patch",
+		"do work":       "does work",
+		"go home":       "goes home",
+		"have items":    "has items",
+		"is valid":      "is valid",
+		"are equal":     "are equal",
+		"user service":  "user service",
+		"JSON encoding": "JSON encoding",
+	} {
+		if got := conjugate(words); got != want {
+			t.Errorf("conjugate(%q) = %q, want %q", words, got, want)
+		}
+	}
+	for verb, want := range map[string]string{"play": "plays", "try": "tries", "push": "pushes", "be": "is"} {
+		if got := thirdPerson(verb); got != want {
+			t.Errorf("thirdPerson(%q) = %q, want %q", verb, got, want)
+		}
+	}
+}
+
+func TestExtraVerbs(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": "package p\n\nfunc CreateUser() {}\n\nfunc ReticulateSplines() {}\n"})
+	if _, stderr, code := run(t, dir, "-auto-description", "-verbs", "reticulate"); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	got := readTree(t, dir)["a.go"]
+	for _, want := range []string{"// CreateUser creates user.\n", "// ReticulateSplines reticulates splines.\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("a.go lacks %q:\n%s", want, got)
+		}
+	}
+}
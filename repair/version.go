@@ -0,0 +1,25 @@
+package repair
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+)
+
+// printVersion prints the version of the binary, of Go and of the modules it was built with,
+// from the build info embedded by the go command.
+func printVersion(out io.Writer) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		fmt.Fprintln(out, "godoc-repair: no build info")
+		return
+	}
+	fmt.Fprintf(out, "%s %s\n", info.Main.Path, info.Main.Version)
+	fmt.Fprintf(out, "go %s\n", info.GoVersion)
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		fmt.Fprintf(out, "%s %s\n", dep.Path, dep.Version)
+	}
+}
@@ -0,0 +1,21 @@
+package repair
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	stdout, stderr, code := run(t, t.TempDir(), "-version")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	lines := strings.Split(stdout, "\n")
+	// a binary built from the sources has the version (devel), an installed one its module version
+	if main := strings.Fields(lines[0]); len(main) != 2 || main[0] != "github.com/xiaoyuanhao/godoc-repair" {
+		t.Errorf("first line %q, want the main module path and its version", lines[0])
+	}
+	if !strings.Contains(stdout, "\ngithub.com/dave/dst v") {
+		t.Errorf("the version of dst is missing:\n%s", stdout)
+	}
+}
@@ -0,0 +1,145 @@
+package repair
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+)
+
+// workspace is set when the run covers the modules of a go.work file.
+var workspace bool
+
+// workspaceModules returns the directories of the modules used by the go.work file of dir,
+// relative to dir in slash form and without the excluded ones, nil if dir has no go.work file.
+func workspaceModules(dir string, exclude []string) ([]string, error) {
+	fileName := filepath.Join(dir, "go.work")
+	data, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed reading %s: %v", fileName, err)
+	}
+	work, err := modfile.ParseWork(fileName, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing %s: %v", fileName, err)
+	}
+	fsys := os.DirFS(dir)
+	modules := []string{}
+	for _, use := range work.Use {
+		module := path.Clean(filepath.ToSlash(use.Path))
+		if filepath.IsAbs(use.Path) || module == ".." || len(module) > 2 && module[:3] == "../" {
+			return nil, fmt.Errorf("module %s of %s is outside of the workspace", use.Path, fileName)
+		}
+		if !matchModule(exclude, module, modulePath(fsys, module)) {
+			modules = append(modules, module)
+		}
+	}
+	return modules, nil
+}
+
+// dedupeRoots returns roots, directories relative to dir, without those listed before under another
+// spelling or through a symlink, and the roots dropped. Roots nested in others are kept, they are modules
+// of their own which the walk of the enclosing root skips, so every directory is visited at most once.
+func dedupeRoots(dir string, roots []string) ([]string, []string) {
+	seen := make(map[string]bool)
+	var kept, dropped []string
+	for _, root := range roots {
+		canonical, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.FromSlash(root)))
+		if err != nil {
+			canonical = filepath.Join(dir, filepath.FromSlash(root))
+		}
+		if seen[canonical] {
+			dropped = append(dropped, root)
+			continue
+		}
+		seen[canonical] = true
+		kept = append(kept, root)
+	}
+	return kept, dropped
+}
+
+// includeModules holds the --module patterns, only the modules matching one of them are processed when set.
+var includeModules stringList
+
+// matchModule reports whether one of patterns matches the module rooted at dir, either its module path
+// such as example.com/legacy or dir relative to the code path in slash form, globs are allowed for both.
+func matchModule(patterns []string, dir, modPath string) bool {
+	for _, pattern := range patterns {
+		pattern = path.Clean(filepath.ToSlash(pattern))
+		if modPath != "" && (pattern == modPath || matchGlob(pattern, modPath)) {
+			return true
+		}
+		if pattern == dir || matchGlob(pattern, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob is path.Match ignoring malformed patterns.
+func matchGlob(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// moduleSelected reports whether the module rooted at dir is processed according to --module
+// and --exclude-module.
+func moduleSelected(dir, modPath string) bool {
+	if len(includeModules) > 0 && !matchModule(includeModules, dir, modPath) {
+		return false
+	}
+	return !matchModule(excludeModules, dir, modPath)
+}
+
+// modulePath returns the module path declared by the go.mod file of dir, empty if there is none.
+func modulePath(fsys fs.FS, dir string) string {
+	data, err := fs.ReadFile(fsys, path.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	return modfile.ModulePath(data)
+}
+
+// owningModule returns the root directory and path of the module dir belongs to, found by looking
+// for the nearest go.mod file upwards, also above the root of fsys when it is a directory of the host.
+// The root is empty when the module lies above fsys and both are empty when there is no module.
+func owningModule(fsys fs.FS, dir string) (string, string) {
+	for d := dir; ; d = path.Dir(d) {
+		if modPath := modulePath(fsys, d); modPath != "" {
+			return d, modPath
+		}
+		if d == "." || d == "/" {
+			break
+		}
+	}
+	if host, ok := fsys.(dirFS); ok {
+		abs, err := filepath.Abs(host.dir)
+		if err != nil {
+			return "", ""
+		}
+		for d := filepath.Dir(abs); ; d = filepath.Dir(d) {
+			if data, err := os.ReadFile(filepath.Join(d, "go.mod")); err == nil {
+				return "", modfile.ModulePath(data)
+			}
+			if d == filepath.Dir(d) {
+				break
+			}
+		}
+	}
+	return "", ""
+}
+
+// isNestedModule reports whether dir below root is the root of another module, which in a workspace
+// is either walked on its own or not a member of the workspace.
+func isNestedModule(fsys fs.FS, root, dir string) bool {
+	if !workspace || dir == root {
+		return false
+	}
+	_, err := fs.Stat(fsys, path.Join(dir, "go.mod"))
+	return err == nil
+}
@@ -0,0 +1,42 @@
+package repair
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOverlappingRoots(t *testing.T) {
+	files := map[string]string{
+		"go.work":                "go 1.18\n\nuse (\n\t./pkg\n\tpkg/\n\t./pkg/storage\n)\n",
+		"pkg/go.mod":             "module example.com/pkg\n\ngo 1.18\n",
+		"pkg/a.go":               "package pkg\n\nfunc A() {}\n",
+		"pkg/storage/go.mod":     "module example.com/storage\n\ngo 1.18\n",
+		"pkg/storage/storage.go": "package storage\n\nfunc Open() {}\n",
+	}
+	dir := writeTree(t, files)
+	before := readTree(t, dir)
+	// positional roots are not taken, nested in each other they would be walked twice
+	_, stderr, code := run(t, dir, "./pkg", "./pkg/storage")
+	if code != 2 || !strings.Contains(stderr, "unexpected arguments ./pkg ./pkg/storage") {
+		t.Errorf("exit status %d, stderr %q, want a usage error", code, stderr)
+	}
+	if !reflect.DeepEqual(readTree(t, dir), before) {
+		t.Fatal("the tree was changed")
+	}
+	// go.work entries listed twice are walked once, a nested module is walked on its own
+	_, stderr, code = run(t, dir)
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if !strings.Contains(stderr, "Skipping the modules listed again in go.work: pkg") {
+		t.Errorf("the repeated module is not reported: %s", stderr)
+	}
+	after := readTree(t, dir)
+	if got := after["pkg/a.go"]; got != "package pkg\n\n// A missing godoc.\nfunc A() {}\n" {
+		t.Errorf("pkg/a.go = %q", got)
+	}
+	if got := after["pkg/storage/storage.go"]; got != "package storage\n\n// Open missing godoc.\nfunc Open() {}\n" {
+		t.Errorf("pkg/storage/storage.go = %q", got)
+	}
+}